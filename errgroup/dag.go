@@ -0,0 +1,122 @@
+// Copyright 2026 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package errgroup
+
+import (
+	"context"
+	"fmt"
+	"sort"
+)
+
+// A DAG is a set of named tasks with dependencies between them, run by Run
+// with as much parallelism as the dependencies allow. The zero DAG is empty
+// and ready to use.
+type DAG struct {
+	tasks map[string]*dagTask
+	order []string // task names in the order they were added
+}
+
+type dagTask struct {
+	deps []string
+	f    func(context.Context) error
+	done chan struct{} // closed when f has returned nil
+}
+
+// AddTask adds a task called name that calls f once every task named in
+// deps has completed successfully. The tasks in deps need not have been
+// added yet, but must be by the time Run is called.
+//
+// AddTask returns an error if a task called name has already been added.
+func (d *DAG) AddTask(name string, deps []string, f func(ctx context.Context) error) error {
+	if _, ok := d.tasks[name]; ok {
+		return fmt.Errorf("errgroup: duplicate task %q", name)
+	}
+	if d.tasks == nil {
+		d.tasks = make(map[string]*dagTask)
+	}
+	d.tasks[name] = &dagTask{deps: append([]string(nil), deps...), f: f}
+	d.order = append(d.order, name)
+	return nil
+}
+
+// Run runs the tasks of d, each in its own goroutine as soon as its
+// dependencies have completed, and waits for them to finish.
+//
+// The Context passed to each task is derived from ctx and is canceled the
+// first time a task returns a non-nil error; tasks that have not started by
+// then are not run, and Run returns that first error.
+//
+// Before running anything, Run checks that every dependency names a task of
+// d and that the dependencies contain no cycle, returning an error if not.
+func (d *DAG) Run(ctx context.Context) error {
+	if err := d.check(); err != nil {
+		return err
+	}
+
+	g, ctx := WithContext(ctx)
+	for _, name := range d.order {
+		t := d.tasks[name]
+		t.done = make(chan struct{})
+	}
+	for _, name := range d.order {
+		t := d.tasks[name]
+		g.Go(func() error {
+			for _, dep := range t.deps {
+				select {
+				case <-d.tasks[dep].done:
+				case <-ctx.Done():
+					return ctx.Err()
+				}
+			}
+			if err := t.f(ctx); err != nil {
+				return err
+			}
+			close(t.done)
+			return nil
+		})
+	}
+	return g.Wait()
+}
+
+// check reports an unknown dependency or a dependency cycle in d.
+func (d *DAG) check() error {
+	// Kahn's algorithm: repeatedly remove the tasks with no remaining
+	// dependencies; whatever cannot be removed is on or behind a cycle.
+	remaining := make(map[string]int, len(d.tasks))
+	dependents := make(map[string][]string)
+	var ready []string
+	for _, name := range d.order {
+		t := d.tasks[name]
+		for _, dep := range t.deps {
+			if _, ok := d.tasks[dep]; !ok {
+				return fmt.Errorf("errgroup: task %q depends on unknown task %q", name, dep)
+			}
+			dependents[dep] = append(dependents[dep], name)
+		}
+		remaining[name] = len(t.deps)
+		if len(t.deps) == 0 {
+			ready = append(ready, name)
+		}
+	}
+	for len(ready) > 0 {
+		name := ready[len(ready)-1]
+		ready = ready[:len(ready)-1]
+		delete(remaining, name)
+		for _, next := range dependents[name] {
+			if remaining[next]--; remaining[next] == 0 {
+				ready = append(ready, next)
+			}
+		}
+	}
+	if len(remaining) > 0 {
+		cycle := make([]string, 0, len(remaining))
+		for name := range remaining {
+			cycle = append(cycle, name)
+		}
+		sort.Strings(cycle)
+		return fmt.Errorf("errgroup: dependency cycle among tasks %q", cycle)
+	}
+	return nil
+}
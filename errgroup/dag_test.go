@@ -0,0 +1,95 @@
+// Copyright 2026 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package errgroup_test
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"sync"
+	"testing"
+
+	"golang.org/x/sync/errgroup"
+)
+
+func TestDAGDiamond(t *testing.T) {
+	var (
+		mu    sync.Mutex
+		order []string
+	)
+	task := func(name string) func(context.Context) error {
+		return func(context.Context) error {
+			mu.Lock()
+			defer mu.Unlock()
+			order = append(order, name)
+			return nil
+		}
+	}
+
+	// a -> {b, c} -> d, added out of order.
+	var d errgroup.DAG
+	d.AddTask("d", []string{"b", "c"}, task("d"))
+	d.AddTask("b", []string{"a"}, task("b"))
+	d.AddTask("c", []string{"a"}, task("c"))
+	d.AddTask("a", nil, task("a"))
+	if err := d.AddTask("a", nil, task("a")); err == nil {
+		t.Error("AddTask of a duplicate task succeeded")
+	}
+
+	if err := d.Run(context.Background()); err != nil {
+		t.Fatalf("Run() = %v", err)
+	}
+	pos := make(map[string]int)
+	for i, name := range order {
+		pos[name] = i
+	}
+	if len(order) != 4 || pos["a"] != 0 || pos["d"] != 3 {
+		t.Errorf("tasks ran in order %q; want a first and d last", order)
+	}
+}
+
+func TestDAGError(t *testing.T) {
+	failure := errors.New("dag_test: failed")
+	ran := false
+	var d errgroup.DAG
+	d.AddTask("a", nil, func(context.Context) error { return failure })
+	d.AddTask("b", []string{"a"}, func(context.Context) error {
+		ran = true
+		return nil
+	})
+	if err := d.Run(context.Background()); err != failure {
+		t.Errorf("Run() = %v; want %v", err, failure)
+	}
+	if ran {
+		t.Error("task ran after its dependency failed")
+	}
+}
+
+func TestDAGCycle(t *testing.T) {
+	ran := false
+	f := func(context.Context) error {
+		ran = true
+		return nil
+	}
+	var d errgroup.DAG
+	d.AddTask("a", nil, f)
+	d.AddTask("b", []string{"a", "d"}, f)
+	d.AddTask("c", []string{"b"}, f)
+	d.AddTask("d", []string{"c"}, f)
+
+	err := d.Run(context.Background())
+	if err == nil || !strings.Contains(err.Error(), `["b" "c" "d"]`) {
+		t.Errorf("Run() = %v; want a cycle among b, c, and d", err)
+	}
+	if ran {
+		t.Error("Run with a cycle ran tasks")
+	}
+
+	var u errgroup.DAG
+	u.AddTask("a", []string{"missing"}, f)
+	if err := u.Run(context.Background()); err == nil {
+		t.Error("Run with an unknown dependency succeeded")
+	}
+}
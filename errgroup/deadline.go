@@ -0,0 +1,69 @@
+// Copyright 2026 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package errgroup
+
+import (
+	"container/heap"
+	"time"
+)
+
+// GoDeadline is like Go, but never blocks: if the group is at the limit set
+// by SetLimit, f is queued, and each time an active goroutine returns, its
+// slot goes to the queued function with the earliest deadline, ahead of any
+// caller blocked in Go. Functions with equal deadlines run in the order in
+// which they were queued. The deadline only orders the queue; it is not
+// enforced. Wait waits for the queued functions too.
+func (g *Group) GoDeadline(deadline time.Time, f func() error) {
+	g.checkStrict()
+	if g.sem == nil {
+		g.launch(f, false)
+		return
+	}
+	g.mu.Lock()
+	if len(g.pending) == 0 {
+		select {
+		case g.sem <- token{}:
+			g.mu.Unlock()
+			g.launch(f, true)
+			return
+		default:
+		}
+	}
+	g.wg.Add(1) // Released by the goroutine that eventually runs f.
+	heap.Push(&g.pending, &pendingTask{deadline: deadline, seq: g.pendingSeq, f: f})
+	g.pendingSeq++
+	g.mu.Unlock()
+}
+
+// A pendingTask is a function queued by GoDeadline.
+type pendingTask struct {
+	deadline time.Time
+	seq      int // order of arrival, to break ties
+	f        func() error
+}
+
+// A pendingQueue is a heap of pending tasks, earliest deadline first.
+type pendingQueue []*pendingTask
+
+func (q pendingQueue) Len() int { return len(q) }
+
+func (q pendingQueue) Less(i, j int) bool {
+	if !q[i].deadline.Equal(q[j].deadline) {
+		return q[i].deadline.Before(q[j].deadline)
+	}
+	return q[i].seq < q[j].seq
+}
+
+func (q pendingQueue) Swap(i, j int) { q[i], q[j] = q[j], q[i] }
+
+func (q *pendingQueue) Push(x interface{}) { *q = append(*q, x.(*pendingTask)) }
+
+func (q *pendingQueue) Pop() interface{} {
+	old := *q
+	t := old[len(old)-1]
+	old[len(old)-1] = nil
+	*q = old[:len(old)-1]
+	return t
+}
@@ -7,21 +7,88 @@
 package errgroup
 
 import (
+	"container/heap"
 	"context"
+	"errors"
+	"fmt"
 	"sync"
+	"sync/atomic"
+	"time"
 )
 
+type token struct{}
+
+// ErrGroupFull is returned by GoOrErr when the group has reached its limit
+// of active goroutines.
+var ErrGroupFull = errors.New("errgroup: group is at its limit of active goroutines")
+
+// ErrWaitReturned is returned by GoOrErr for a group in strict mode whose
+// Wait method has returned; see SetStrict.
+var ErrWaitReturned = errors.New("errgroup: Go called after Wait returned")
+
+// ErrBatchTimeout is returned by Wait for a group created by WithTimeout
+// whose deadline passed before any of its goroutines failed.
+var ErrBatchTimeout = errors.New("errgroup: batch timed out")
+
 // A Group is a collection of goroutines working on subtasks that are part of
 // the same overall task.
 //
 // A zero Group is valid and does not cancel on error.
 type Group struct {
+	ctx    context.Context // nil for a zero Group
 	cancel func()
 
 	wg sync.WaitGroup
 
+	sem       chan token   // see SetLimit
+	limitWait atomic.Int64 // see LimitWaitTime
+
+	softLimit int              // see SetSoftLimit
+	onExceed  func(active int) // see SetSoftLimit
+
+	mu     sync.Mutex
+	active int           // number of running goroutines; guarded by mu
+	done   chan struct{} // see Done; guarded by mu
+
 	errOnce sync.Once
 	err     error
+	errs    []error // all errors, in the order returned; guarded by mu
+	joined  error   // errs joined, when len(errs) was joinedN; guarded by mu
+	joinedN int
+
+	total     int // number of goroutines started; guarded by mu
+	failures  int // number of functions that returned an error; guarded by mu
+	threshold int // see SetErrorThreshold
+
+	waitOnce   sync.Once
+	afterFuncs []*afterFunc // for a zero Group; guarded by mu
+	waited     bool         // Wait has run afterFuncs; guarded by mu
+
+	reports []TaskReport // see GoNamed; guarded by mu
+	named   []*namedTask // see GoNamed and Retry; guarded by mu
+	timings []TaskTiming // see GoTimed; guarded by mu
+
+	warnings []error // see GoCtxWarn; guarded by mu
+
+	pending    pendingQueue // see GoDeadline; guarded by mu
+	pendingSeq int          // guarded by mu
+
+	logger   Logger
+	spawn    func(func())                                    // see SetSpawner
+	stacks   bool                                            // see CaptureErrorStacks
+	recover  bool                                            // see SetRecover
+	onPanic  func(recovered interface{}, stack []byte) error // see SetPanicHandler
+	collect  bool                                            // see SetCollectAll
+	noCancel bool                                            // see SetCancelOnError
+
+	strict       bool        // see SetStrict
+	waitReturned atomic.Bool // Wait has returned; see SetStrict
+}
+
+// A Logger receives structured log events from a Group. The key-value pairs
+// in kv alternate between string keys and arbitrary values.
+type Logger interface {
+	Log(level, msg string, kv ...interface{})
 }
 
 // WithContext returns a new Group and an associated Context derived from ctx.
@@ -31,36 +98,524 @@ type Group struct {
 // first.
 func WithContext(ctx context.Context) (*Group, context.Context) {
 	ctx, cancel := context.WithCancel(ctx)
-	return &Group{cancel: cancel}, ctx
+	return &Group{ctx: ctx, cancel: cancel}, ctx
+}
+
+// Context returns the Context associated with the group by WithContext,
+// which is canceled when the group is, or context.Background for a zero
+// Group. It lets code that has the group but not its Context observe
+// cancelation without being able to cancel the group itself.
+func (g *Group) Context() context.Context {
+	if g.ctx == nil {
+		return context.Background()
+	}
+	return g.ctx
+}
+
+// AfterFunc arranges to call f in its own goroutine once the group's Context
+// is canceled, as context.AfterFunc does, for teardown tied to the lifetime
+// of the group. For a zero Group, which has no Context, f is instead called
+// when Wait first returns. If the group has already reached that point, f is
+// called at once.
+//
+// Calling the returned stop function stops the association of f with the
+// group. It returns true if the call stopped f from being run.
+func (g *Group) AfterFunc(f func()) (stop func() bool) {
+	if g.ctx != nil {
+		return context.AfterFunc(g.ctx, f)
+	}
+	a := &afterFunc{f: f}
+	g.mu.Lock()
+	if g.waited {
+		g.mu.Unlock()
+		go f()
+		return func() bool { return false }
+	}
+	g.afterFuncs = append(g.afterFuncs, a)
+	g.mu.Unlock()
+	return func() bool {
+		g.mu.Lock()
+		defer g.mu.Unlock()
+		stopped := !a.done
+		a.done = true
+		return stopped
+	}
+}
+
+// An afterFunc is a function registered with AfterFunc on a zero Group.
+type afterFunc struct {
+	f    func()
+	done bool // started or stopped; guarded by Group.mu
+}
+
+// runAfterFuncs starts the functions registered with AfterFunc on a zero
+// Group.
+func (g *Group) runAfterFuncs() {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	g.waited = true
+	for _, a := range g.afterFuncs {
+		if !a.done {
+			a.done = true
+			go a.f()
+		}
+	}
+	g.afterFuncs = nil
+}
+
+// WithTimeout is like WithContext, but also limits the whole batch of
+// goroutines to a duration of d: once d has elapsed, the derived Context is
+// canceled, and unless a function passed to Go returned a non-nil error
+// before then, Wait returns ErrBatchTimeout.
+func WithTimeout(ctx context.Context, d time.Duration) (*Group, context.Context) {
+	ctx, cancel := context.WithTimeoutCause(ctx, d, ErrBatchTimeout)
+	return &Group{ctx: ctx, cancel: cancel}, ctx
+}
+
+// WithContexts is like WithContext, but derives the group's Context from
+// several parents: it is canceled as soon as any of ctxs is done, with that
+// parent's cause, as well as when a function passed to Go fails or Wait
+// returns. Its values and the deadline reported by its Deadline method are
+// those of the first of ctxs. With no ctxs, the Context is derived from
+// context.Background.
+func WithContexts(ctxs ...context.Context) (*Group, context.Context) {
+	if len(ctxs) == 0 {
+		return WithContext(context.Background())
+	}
+	ctx, cancel := context.WithCancelCause(ctxs[0])
+	for _, parent := range ctxs[1:] {
+		stop := context.AfterFunc(parent, func() {
+			cancel(context.Cause(parent))
+		})
+		// Don't keep ctx reachable from parent once it no longer matters.
+		context.AfterFunc(ctx, func() { stop() })
+	}
+	return &Group{ctx: ctx, cancel: func() { cancel(nil) }}, ctx
+}
+
+// timedOut reports whether the group's Context was canceled because the
+// deadline set by WithTimeout passed.
+func (g *Group) timedOut() bool {
+	return g.ctx != nil && context.Cause(g.ctx) == ErrBatchTimeout
+}
+
+// Scope returns a Context derived from the group's Context along with a done
+// function that cancels it. Tasks that observe a scope's Context can be
+// canceled as a unit by calling its done function, leaving the rest of the
+// group running, while canceling the group still cancels every scope.
+//
+// For a zero Group, the scope is derived from context.Background.
+//
+// The done function must be called once the scope's tasks have returned in
+// order to release the resources associated with the scope.
+func (g *Group) Scope() (ctx context.Context, done func()) {
+	return context.WithCancel(g.Context())
 }
 
 // Wait blocks until all function calls from the Go method have returned, then
 // returns the first non-nil error (if any) from them.
+//
+// Wait may be called more than once, including concurrently. Every call
+// returns the same error, unless more goroutines are started with Go after
+// an earlier call returned, and the group's Context is canceled only by the
+// first call to return.
 func (g *Group) Wait() error {
 	g.wg.Wait()
-	if g.cancel != nil {
-		g.cancel()
+	g.waitOnce.Do(func() {
+		if g.timedOut() {
+			// The timeout becomes the group's error unless a function
+			// failed first. It is not the failure of any function, so it
+			// is neither added to errs nor counted toward the threshold.
+			g.errOnce.Do(func() {
+				g.err = ErrBatchTimeout
+			})
+		}
+		if g.cancel != nil {
+			g.cancel()
+		}
+		g.runAfterFuncs()
+	})
+	g.waitReturned.Store(true)
+	return g.result()
+}
+
+// WaitContext is like Wait, but also returns ctx.Err() as soon as ctx is
+// done, without waiting for the function calls from the Go method that are
+// still running. Those goroutines keep running unless they observe a
+// canceled Context themselves, and a later call to Wait or WaitContext can
+// still collect their result.
+func (g *Group) WaitContext(ctx context.Context) error {
+	select {
+	case <-g.Done():
+		return g.Wait()
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// result returns the error to be reported by Wait.
+func (g *Group) result() error {
+	if !g.collect {
+		return g.err
+	}
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	if len(g.errs) == 0 {
+		// No function failed, but the batch may have timed out.
+		return g.err
+	}
+	if g.joined == nil || len(g.errs) != g.joinedN {
+		// Join the errors once, so that each call to Wait returns the
+		// same error value.
+		g.joined = errors.Join(g.errs...)
+		g.joinedN = len(g.errs)
+	}
+	return g.joined
+}
+
+// SetCollectAll controls whether the group keeps every error rather than
+// only the first. In collect-all mode, Wait returns all the non-nil errors
+// returned by the functions passed to Go, joined with errors.Join in the
+// order in which they were returned.
+//
+// SetCollectAll does not change when the group's Context is canceled; see
+// SetCancelOnError. By default, then, the first error still cancels the
+// Context so that the remaining goroutines can stop early, and the errors
+// they return as they wind down are collected along with it.
+//
+// SetCollectAll must be called before any goroutine is started with Go.
+func (g *Group) SetCollectAll(enabled bool) {
+	g.collect = enabled
+}
+
+// SetCancelOnError controls whether the first non-nil error returned by a
+// function passed to Go cancels the Context returned by WithContext, which is
+// the default. With cancelation disabled, the remaining goroutines run to
+// completion, which together with SetCollectAll allows a batch to report
+// every outcome; the Context is still canceled when Wait returns.
+//
+// SetCancelOnError must be called before any goroutine is started with Go.
+func (g *Group) SetCancelOnError(enabled bool) {
+	g.noCancel = !enabled
+}
+
+// SetErrorThreshold makes the group tolerate failures: the Context returned
+// by WithContext is canceled once n of the functions passed to Go have
+// returned a non-nil error, rather than on the first one. It also enables
+// SetCollectAll, so that Wait returns every error.
+//
+// An n <= 1 restores the default of canceling on the first error.
+// SetCancelOnError(false) still disables cancelation altogether.
+//
+// SetErrorThreshold must be called before any goroutine is started with Go.
+func (g *Group) SetErrorThreshold(n int) {
+	g.threshold = n
+	if n > 1 {
+		g.collect = true
+	}
+}
+
+// Done returns a channel that is closed when all function calls from the Go
+// method have returned, that is, once Wait would return without blocking.
+// If Go is called again after the channel has been closed, subsequent calls
+// to Done return a new channel for the new set of goroutines.
+func (g *Group) Done() <-chan struct{} {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	if g.done == nil {
+		g.done = make(chan struct{})
+		if g.active == 0 {
+			close(g.done)
+		}
+	}
+	return g.done
+}
+
+func (g *Group) start() {
+	g.wg.Add(1)
+	g.mu.Lock()
+	if g.active == 0 {
+		// Any existing done channel is closed; the next call to Done
+		// must wait for this goroutine.
+		g.done = nil
+	}
+	g.active++
+	g.total++
+	active := g.active
+	g.mu.Unlock()
+	if g.onExceed != nil && active > g.softLimit {
+		g.onExceed(active)
+	}
+}
+
+// finish ends a goroutine started with start. If it has a slot in g.sem,
+// finish hands the slot over to the earliest pending GoDeadline task, if
+// any, or else releases it.
+func (g *Group) finish(slot bool) {
+	g.mu.Lock()
+	if slot && len(g.pending) > 0 {
+		// The next task takes the place of this one, so the number of
+		// active goroutines does not change, and it was counted in g.wg
+		// when it was queued.
+		next := heap.Pop(&g.pending).(*pendingTask)
+		g.total++
+		g.mu.Unlock()
+		g.spawnTask(next.f, true)
+		g.wg.Done()
+		return
+	}
+	if slot {
+		// Release the slot with g.mu held, so that GoDeadline cannot queue
+		// a task after failing to take it.
+		<-g.sem
+	}
+	g.active--
+	if g.active == 0 && g.done != nil {
+		close(g.done)
+	}
+	g.mu.Unlock()
+	g.wg.Done()
+}
+
+// SetLogger makes the group log the lifecycle of its goroutines to l: the
+// start of each goroutine, its completion along with its duration and error,
+// any panic, and the cancelation of the group by a failing goroutine.
+// Events are logged at level "debug", except for failures, panics, and
+// cancelation, which are logged at level "error".
+//
+// SetLogger must be called before any goroutine is started with Go.
+// A nil l disables logging.
+func (g *Group) SetLogger(l Logger) {
+	g.logger = l
+}
+
+// SetSpawner makes Go start its goroutines by calling spawn instead of
+// using a go statement, for example to run them on a goroutine pool.
+// spawn must arrange for the function it is given to be called exactly
+// once; it may call it synchronously.
+//
+// SetSpawner must be called before any goroutine is started with Go.
+// A nil spawn restores the default.
+func (g *Group) SetSpawner(spawn func(func())) {
+	g.spawn = spawn
+}
+
+// run calls f, logging its lifecycle if the group has a Logger and
+// converting a panic into an error if the group recovers panics.
+func (g *Group) run(f func() error) (err error) {
+	l := g.logger
+	if l == nil && !g.recover {
+		return f()
+	}
+
+	var start time.Time
+	if l != nil {
+		start = time.Now()
+		l.Log("debug", "errgroup: goroutine started")
+	}
+	normalReturn := false
+	defer func() {
+		if normalReturn {
+			return
+		}
+		r := recover()
+		if r == nil {
+			return // f called runtime.Goexit.
+		}
+		if l != nil {
+			l.Log("error", "errgroup: goroutine panicked", "panic", r, "duration", time.Since(start))
+		}
+		if !g.recover {
+			panic(r)
+		}
+		err = g.panicError(r)
+	}()
+
+	err = f()
+	normalReturn = true
+	if l != nil {
+		level := "debug"
+		if err != nil {
+			level = "error"
+		}
+		l.Log(level, "errgroup: goroutine finished", "duration", time.Since(start), "error", err)
 	}
-	return g.err
+	return err
 }
 
 // Go calls the given function in a new goroutine.
+// It blocks until the new goroutine can be added without the number of
+// active goroutines in the group exceeding the configured limit.
 //
 // The first call to return a non-nil error cancels the group; its error will be
 // returned by Wait.
 func (g *Group) Go(f func() error) {
-	g.wg.Add(1)
+	g.checkStrict()
+	if g.sem != nil {
+		select {
+		case g.sem <- token{}:
+		default:
+			start := time.Now()
+			g.sem <- token{}
+			g.limitWait.Add(int64(time.Since(start)))
+		}
+	}
+	g.launch(f, g.sem != nil)
+}
 
-	go func() {
-		defer g.wg.Done()
+// GoUnlimited is like Go, but never blocks: it starts f without taking one
+// of the slots limited by SetLimit, so that urgent work can run at once in a
+// group whose limit is reached. f still counts as an active goroutine, for
+// Wait and SetSoftLimit for instance, and its error is handled as for Go,
+// but it does not delay the goroutines started by later calls to Go.
+func (g *Group) GoUnlimited(f func() error) {
+	g.checkStrict()
+	g.launch(f, false)
+}
 
-		if err := f(); err != nil {
-			g.errOnce.Do(func() {
-				g.err = err
-				if g.cancel != nil {
-					g.cancel()
-				}
-			})
+// LimitWaitTime returns the total time that calls to Go have spent blocked
+// waiting for the number of active goroutines to drop below the limit set
+// with SetLimit.
+func (g *Group) LimitWaitTime() time.Duration {
+	return time.Duration(g.limitWait.Load())
+}
+
+// TryGo calls the given function in a new goroutine only if the number of
+// active goroutines in the group is currently below the configured limit.
+//
+// The return value reports whether the goroutine was started.
+func (g *Group) TryGo(f func() error) bool {
+	g.checkStrict()
+	if g.sem != nil {
+		select {
+		case g.sem <- token{}:
+			// Note: this allows barging iff channels in general allow barging.
+		default:
+			return false
 		}
-	}()
+	}
+	g.launch(f, g.sem != nil)
+	return true
+}
+
+// GoOrErr is like TryGo, but reports a group at its limit by returning
+// ErrGroupFull rather than false, so that it composes with code that returns
+// errors. f is not called if GoOrErr returns ErrGroupFull.
+//
+// In strict mode, GoOrErr returns ErrWaitReturned instead of panicking once
+// Wait has returned; see SetStrict.
+func (g *Group) GoOrErr(f func() error) error {
+	if g.strict && g.waitReturned.Load() {
+		return ErrWaitReturned
+	}
+	if !g.TryGo(f) {
+		return ErrGroupFull
+	}
+	return nil
+}
+
+// launch starts f. slot reports whether the caller has taken a slot from
+// g.sem on behalf of f.
+func (g *Group) launch(f func() error, slot bool) {
+	g.start()
+	g.spawnTask(f, slot)
+}
+
+// spawnTask runs f, already counted by start, in a new goroutine.
+func (g *Group) spawnTask(f func() error, slot bool) {
+	g.goFunc(func() {
+		defer g.finish(slot)
+
+		if err := g.run(f); err != nil {
+			if g.stacks {
+				err = newStackError(err, f)
+			}
+			g.fail(err)
+		}
+	})
+}
+
+// SetStrict controls whether the group rejects new goroutines once Wait has
+// returned. A goroutine started after Wait has returned is not waited for by
+// that call, which is almost always a bug; in strict mode, Go, TryGo, and
+// GoUnlimited panic and GoOrErr returns ErrWaitReturned instead of starting
+// it. Strict mode is disabled by default, so that a group can be reused
+// after Wait.
+//
+// SetStrict must be called before any goroutine is started with Go.
+func (g *Group) SetStrict(enabled bool) {
+	g.strict = enabled
+}
+
+// checkStrict panics if g is in strict mode and Wait has returned.
+func (g *Group) checkStrict() {
+	if g.strict && g.waitReturned.Load() {
+		panic(ErrWaitReturned)
+	}
+}
+
+// SetSoftLimit sets a soft limit of n active goroutines: each time Go or
+// TryGo starts a goroutine that brings the number of active goroutines in the
+// group above n, onExceed is called with that number, on the calling
+// goroutine, before the goroutine runs. Unlike SetLimit, the soft limit
+// never blocks. A nil onExceed removes the soft limit.
+//
+// SetSoftLimit must be called before any goroutine is started with Go.
+func (g *Group) SetSoftLimit(n int, onExceed func(active int)) {
+	g.softLimit = n
+	g.onExceed = onExceed
+}
+
+// SetLimit limits the number of active goroutines in this group to at most n.
+// A negative value indicates no limit.
+// A limit of zero will prevent any new goroutines from being added.
+//
+// Any subsequent call to the Go method will block until it can add an active
+// goroutine without exceeding the configured limit.
+//
+// The limit must not be modified while any goroutines in the group are active.
+func (g *Group) SetLimit(n int) {
+	if n < 0 {
+		g.sem = nil
+		return
+	}
+	if len(g.sem) != 0 {
+		panic(fmt.Errorf("errgroup: modify limit while %v goroutines in the group are still active", len(g.sem)))
+	}
+	g.sem = make(chan token, n)
+}
+
+// fail records err, returned by a function passed to Go.
+func (g *Group) fail(err error) {
+	g.mu.Lock()
+	g.errs = append(g.errs, err)
+	g.failures++
+	failures := g.failures
+	g.mu.Unlock()
+
+	g.errOnce.Do(func() {
+		if g.timedOut() {
+			err = ErrBatchTimeout
+		}
+		g.err = err
+	})
+	threshold := g.threshold
+	if threshold <= 0 {
+		threshold = 1
+	}
+	if failures == threshold && g.cancel != nil && !g.noCancel {
+		g.cancel()
+		if g.logger != nil {
+			g.logger.Log("error", "errgroup: group canceled", "error", err)
+		}
+	}
+}
+
+// goFunc runs f in a new goroutine, or with the group's spawner if it has one.
+func (g *Group) goFunc(f func()) {
+	if g.spawn != nil {
+		g.spawn(f)
+		return
+	}
+	go f()
 }
@@ -5,12 +5,18 @@
 package errgroup_test
 
 import (
+	"bytes"
 	"context"
 	"errors"
 	"fmt"
 	"net/http"
 	"os"
+	"reflect"
+	"strings"
+	"sync"
+	"sync/atomic"
 	"testing"
+	"time"
 
 	"golang.org/x/sync/errgroup"
 )
@@ -174,3 +180,1036 @@ func TestWithContext(t *testing.T) {
 		}
 	}
 }
+
+func TestScope(t *testing.T) {
+	g, ctx := errgroup.WithContext(context.Background())
+
+	canceledCtx, cancel := g.Scope()
+	siblingCtx, siblingDone := g.Scope()
+	defer siblingDone()
+
+	started := make(chan struct{})
+	g.Go(func() error {
+		close(started)
+		<-canceledCtx.Done()
+		return nil
+	})
+	g.Go(func() error {
+		<-started
+		cancel()
+		return siblingCtx.Err()
+	})
+
+	if err := g.Wait(); err != nil {
+		t.Errorf("g.Wait() = %v; want nil", err)
+	}
+	if err := canceledCtx.Err(); err != context.Canceled {
+		t.Errorf("canceled scope: ctx.Err() = %v; want %v", err, context.Canceled)
+	}
+	if err := ctx.Err(); err != context.Canceled {
+		t.Errorf("after Wait, group ctx.Err() = %v; want %v", err, context.Canceled)
+	}
+	if err := siblingCtx.Err(); err != context.Canceled {
+		t.Errorf("after Wait, sibling scope ctx.Err() = %v; want %v", err, context.Canceled)
+	}
+}
+
+func TestDone(t *testing.T) {
+	g := new(errgroup.Group)
+
+	select {
+	case <-g.Done():
+	default:
+		t.Fatal("Done() of a Group without goroutines is not closed")
+	}
+
+	release := make(chan struct{})
+	g.Go(func() error {
+		<-release
+		return nil
+	})
+	done := g.Done()
+
+	select {
+	case <-done:
+		t.Fatal("Done() closed while a goroutine is still running")
+	case <-time.After(10 * time.Millisecond):
+	}
+
+	close(release)
+	select {
+	case <-done:
+	case <-time.After(time.Second):
+		t.Fatal("Done() not closed after all goroutines returned")
+	}
+	if err := g.Wait(); err != nil {
+		t.Errorf("g.Wait() = %v; want nil", err)
+	}
+}
+
+type captureLogger struct {
+	mu     sync.Mutex
+	events []string
+}
+
+func (l *captureLogger) Log(level, msg string, kv ...interface{}) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.events = append(l.events, level+" "+msg)
+}
+
+func TestSetLogger(t *testing.T) {
+	errDoom := errors.New("group_test: doomed")
+	l := new(captureLogger)
+	g, _ := errgroup.WithContext(context.Background())
+	g.SetLogger(l)
+
+	g.Go(func() error { return nil })
+	g.Go(func() error { return errDoom })
+	if err := g.Wait(); err != errDoom {
+		t.Fatalf("g.Wait() = %v; want %v", err, errDoom)
+	}
+
+	count := make(map[string]int)
+	for _, e := range l.events {
+		count[e]++
+	}
+	want := map[string]int{
+		"debug errgroup: goroutine started":  2,
+		"debug errgroup: goroutine finished": 1,
+		"error errgroup: goroutine finished": 1,
+		"error errgroup: group canceled":     1,
+	}
+	if fmt.Sprint(count) != fmt.Sprint(want) {
+		t.Errorf("logged events %v; want %v", count, want)
+	}
+}
+
+func TestSetSpawner(t *testing.T) {
+	g := new(errgroup.Group)
+	spawned := 0
+	g.SetSpawner(func(f func()) {
+		spawned++
+		f()
+	})
+
+	var order []int
+	for i := 0; i < 3; i++ {
+		i := i
+		g.Go(func() error {
+			order = append(order, i)
+			return nil
+		})
+	}
+	if err := g.Wait(); err != nil {
+		t.Errorf("g.Wait() = %v; want nil", err)
+	}
+	if spawned != 3 {
+		t.Errorf("spawner called %d times; want 3", spawned)
+	}
+	if fmt.Sprint(order) != "[0 1 2]" {
+		t.Errorf("goroutines ran in order %v; want [0 1 2]", order)
+	}
+}
+
+func failingTask() error {
+	return errors.New("errgroup_test: failing task")
+}
+
+func TestCaptureErrorStacks(t *testing.T) {
+	g := new(errgroup.Group)
+	g.CaptureErrorStacks(true)
+	g.Go(failingTask)
+	err := g.Wait()
+
+	var se interface{ Stack() []byte }
+	if !errors.As(err, &se) {
+		t.Fatalf("g.Wait() = %v, which does not expose a stack", err)
+	}
+	if got, want := err.Error(), "errgroup_test: failing task"; got != want {
+		t.Errorf("g.Wait().Error() = %q; want %q", got, want)
+	}
+	if stack := se.Stack(); !bytes.Contains(stack, []byte("errgroup_test.failingTask")) {
+		t.Errorf("stack does not mention the task function:\n%s", stack)
+	}
+}
+
+// TestRecoverCollectAll checks that recovering panics, collecting all errors,
+// and not canceling on error compose: a panic becomes one of the collected
+// errors and does not stop the rest of the batch.
+func TestRecoverCollectAll(t *testing.T) {
+	errDoom := errors.New("group_test: doomed")
+	g, ctx := errgroup.WithContext(context.Background())
+	g.SetRecover(true)
+	g.SetCollectAll(true)
+	g.SetCancelOnError(false)
+
+	panicked := make(chan struct{})
+	g.Go(func() error {
+		defer close(panicked)
+		panic("group_test: boom")
+	})
+	g.Go(func() error {
+		<-panicked
+		return errDoom
+	})
+	var completed int32
+	for i := 0; i < 3; i++ {
+		g.Go(func() error {
+			<-panicked
+			if err := ctx.Err(); err != nil {
+				return err
+			}
+			atomic.AddInt32(&completed, 1)
+			return nil
+		})
+	}
+
+	err := g.Wait()
+	if completed != 3 {
+		t.Errorf("%d of 3 goroutines completed after a panic; want all", completed)
+	}
+	if !errors.Is(err, errDoom) {
+		t.Errorf("g.Wait() = %v; want it to include %v", err, errDoom)
+	}
+	if err == nil || !strings.Contains(err.Error(), "group_test: boom") {
+		t.Errorf("g.Wait() = %v; want it to include the panic", err)
+	}
+	if n := len(err.(interface{ Unwrap() []error }).Unwrap()); n != 2 {
+		t.Errorf("g.Wait() joined %d errors; want 2", n)
+	}
+}
+
+func TestWaitConcurrent(t *testing.T) {
+	for _, collect := range []bool{false, true} {
+		g, ctx := errgroup.WithContext(context.Background())
+		g.SetCollectAll(collect)
+		g.Go(func() error { return errors.New("group_test: 1") })
+		g.Go(func() error { return errors.New("group_test: 2") })
+
+		const n = 10
+		errs := make(chan error, n)
+		for i := 0; i < n; i++ {
+			go func() { errs <- g.Wait() }()
+		}
+		first := <-errs
+		if first == nil {
+			t.Fatalf("collect %t: g.Wait() = nil; want an error", collect)
+		}
+		for i := 1; i < n; i++ {
+			if err := <-errs; err != first {
+				t.Errorf("collect %t: concurrent g.Wait() = %v; want %v", collect, err, first)
+			}
+		}
+		if err := g.Wait(); err != first {
+			t.Errorf("collect %t: later g.Wait() = %v; want %v", collect, err, first)
+		}
+		if ctx.Err() == nil {
+			t.Errorf("collect %t: ctx not canceled after g.Wait()", collect)
+		}
+	}
+}
+
+func TestTryGo(t *testing.T) {
+	g := &errgroup.Group{}
+	n := 42
+	g.SetLimit(42)
+	ch := make(chan struct{})
+	fn := func() error {
+		ch <- struct{}{}
+		return nil
+	}
+	for i := 0; i < n; i++ {
+		if !g.TryGo(fn) {
+			t.Fatalf("TryGo should succeed but got fail at %d-th call.", i)
+		}
+	}
+	if g.TryGo(fn) {
+		t.Fatalf("TryGo is expected to fail but succeeded.")
+	}
+	go func() {
+		for i := 0; i < n; i++ {
+			<-ch
+		}
+	}()
+	g.Wait()
+
+	if !g.TryGo(fn) {
+		t.Fatalf("TryGo should success but got fail after all goroutines.")
+	}
+	go func() { <-ch }()
+	g.Wait()
+
+	// Switch limit.
+	g.SetLimit(1)
+	if !g.TryGo(fn) {
+		t.Fatalf("TryGo should success but got failed.")
+	}
+	if g.TryGo(fn) {
+		t.Fatalf("TryGo should fail but succeeded.")
+	}
+	go func() { <-ch }()
+	g.Wait()
+
+	// Block all calls.
+	g.SetLimit(0)
+	for i := 0; i < 1<<10; i++ {
+		if g.TryGo(fn) {
+			t.Fatalf("TryGo should fail but got succeded.")
+		}
+	}
+	g.Wait()
+}
+
+func TestGoLimit(t *testing.T) {
+	const limit = 10
+
+	g := &errgroup.Group{}
+	g.SetLimit(limit)
+	var active int32
+	for i := 0; i <= 1<<10; i++ {
+		g.Go(func() error {
+			n := atomic.AddInt32(&active, 1)
+			if n > limit {
+				return fmt.Errorf("saw %d active goroutines; want ≤ %d", n, limit)
+			}
+			time.Sleep(1 * time.Microsecond) // Give other goroutines a chance to increment active.
+			atomic.AddInt32(&active, -1)
+			return nil
+		})
+	}
+	if err := g.Wait(); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestGoUnlimited(t *testing.T) {
+	g := &errgroup.Group{}
+	g.SetLimit(1)
+	unblock := make(chan struct{})
+	g.Go(func() error {
+		<-unblock
+		return nil
+	})
+
+	ran := make(chan struct{})
+	g.GoUnlimited(func() error {
+		close(ran)
+		return nil
+	})
+	select {
+	case <-ran:
+	case <-time.After(5 * time.Second):
+		t.Fatal("GoUnlimited did not run its function at the limit")
+	}
+
+	launched := make(chan struct{})
+	go func() {
+		g.Go(func() error { return nil })
+		close(launched)
+	}()
+	select {
+	case <-launched:
+		t.Fatal("Go did not block at the limit")
+	case <-time.After(10 * time.Millisecond):
+	}
+
+	errUrgent := errors.New("errgroup_test: urgent failure")
+	g.GoUnlimited(func() error { return errUrgent })
+	close(unblock)
+	<-launched
+	if err := g.Wait(); err != errUrgent {
+		t.Errorf("g.Wait() = %v; want %v", err, errUrgent)
+	}
+}
+
+func TestSetStrict(t *testing.T) {
+	g := new(errgroup.Group)
+	g.SetStrict(true)
+	g.Go(func() error { return nil })
+	if err := g.Wait(); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := g.GoOrErr(func() error { return nil }); err != errgroup.ErrWaitReturned {
+		t.Errorf("GoOrErr after Wait = %v; want %v", err, errgroup.ErrWaitReturned)
+	}
+	func() {
+		defer func() {
+			if r := recover(); r != errgroup.ErrWaitReturned {
+				t.Errorf("Go after Wait panicked with %v; want %v", r, errgroup.ErrWaitReturned)
+			}
+		}()
+		g.Go(func() error { return nil })
+	}()
+
+	// Without strict mode, the group can be reused.
+	g = new(errgroup.Group)
+	g.Wait()
+	if err := g.GoOrErr(func() error { return nil }); err != nil {
+		t.Errorf("GoOrErr after Wait without strict mode = %v; want nil", err)
+	}
+	g.Wait()
+}
+
+func TestGoDeadline(t *testing.T) {
+	g := new(errgroup.Group)
+	g.SetLimit(1)
+	unblock := make(chan struct{})
+	g.GoDeadline(time.Now(), func() error {
+		<-unblock
+		return nil
+	})
+
+	var order []int
+	base := time.Now()
+	for _, i := range []int{3, 1, 4, 2, 1} {
+		g.GoDeadline(base.Add(time.Duration(i)*time.Second), func() error {
+			order = append(order, i)
+			return nil
+		})
+	}
+	close(unblock)
+	if err := g.Wait(); err != nil {
+		t.Fatal(err)
+	}
+	if want := []int{1, 1, 2, 3, 4}; !reflect.DeepEqual(order, want) {
+		t.Errorf("tasks ran in deadline order %v; want %v", order, want)
+	}
+}
+
+func TestGoOrErr(t *testing.T) {
+	g := &errgroup.Group{}
+	g.SetLimit(1)
+	unblock := make(chan struct{})
+	if err := g.GoOrErr(func() error {
+		<-unblock
+		return nil
+	}); err != nil {
+		t.Fatalf("GoOrErr on an empty group = %v; want nil", err)
+	}
+
+	var ran int32
+	for i := 0; i < 10; i++ {
+		err := g.GoOrErr(func() error {
+			atomic.AddInt32(&ran, 1)
+			return nil
+		})
+		if err != errgroup.ErrGroupFull {
+			t.Fatalf("GoOrErr on a full group = %v; want %v", err, errgroup.ErrGroupFull)
+		}
+	}
+	close(unblock)
+	if err := g.Wait(); err != nil {
+		t.Fatalf("g.Wait() = %v", err)
+	}
+	if n := atomic.LoadInt32(&ran); n != 0 {
+		t.Errorf("%d functions rejected by GoOrErr ran; want 0", n)
+	}
+}
+
+func TestPanicErrorAs(t *testing.T) {
+	g := new(errgroup.Group)
+	g.SetRecover(true)
+	g.Go(func() error {
+		panic("group_test: boom")
+	})
+	err := g.Wait()
+
+	var pe *errgroup.PanicError
+	if !errors.As(err, &pe) {
+		t.Fatalf("errors.As(%v, *PanicError) = false; want true", err)
+	}
+	if pe.Value != "group_test: boom" {
+		t.Errorf("PanicError.Value = %v; want %q", pe.Value, "group_test: boom")
+	}
+	if !bytes.Contains(pe.Stack, []byte("TestPanicErrorAs")) {
+		t.Errorf("PanicError.Stack does not mention the panicking test:\n%s", pe.Stack)
+	}
+
+	// A panic with an error value unwraps to that error.
+	g = new(errgroup.Group)
+	g.SetRecover(true)
+	g.Go(func() error {
+		panic(os.ErrNotExist)
+	})
+	if err := g.Wait(); !errors.Is(err, os.ErrNotExist) {
+		t.Errorf("g.Wait() = %v; want an error wrapping %v", err, os.ErrNotExist)
+	}
+}
+
+func TestSetErrorThreshold(t *testing.T) {
+	g, ctx := errgroup.WithContext(context.Background())
+	g.SetErrorThreshold(3)
+
+	// The tasks fail one at a time; only the third failure cancels ctx.
+	turn := make(chan struct{})
+	for i := 1; i <= 3; i++ {
+		g.Go(func() error {
+			<-turn
+			return fmt.Errorf("group_test: failure %d", i)
+		})
+		turn <- struct{}{}
+		if i < 3 {
+			// Give a cancelation a chance to be observed.
+			select {
+			case <-ctx.Done():
+				t.Fatalf("ctx canceled after %d failures; want 3", i)
+			case <-time.After(10 * time.Millisecond):
+			}
+		}
+	}
+
+	canceled := make(chan struct{})
+	g.Go(func() error {
+		<-ctx.Done()
+		close(canceled)
+		return nil
+	})
+	select {
+	case <-canceled:
+	case <-time.After(5 * time.Second):
+		t.Fatal("remaining task not canceled after the third failure")
+	}
+
+	err := g.Wait()
+	if n := len(err.(interface{ Unwrap() []error }).Unwrap()); n != 3 {
+		t.Errorf("g.Wait() joined %d errors; want 3: %v", n, err)
+	}
+}
+
+func TestCollectAllCancelOnError(t *testing.T) {
+	g, ctx := errgroup.WithContext(context.Background())
+	g.SetCollectAll(true)
+
+	errFirst := errors.New("errgroup_test: first failure")
+	errWindDown := errors.New("errgroup_test: wound down")
+	for i := 0; i < 2; i++ {
+		g.Go(func() error {
+			<-ctx.Done()
+			return fmt.Errorf("task %d: %w: %w", i, errWindDown, context.Cause(ctx))
+		})
+	}
+	g.Go(func() error { return errFirst })
+
+	err := g.Wait()
+	errs := err.(interface{ Unwrap() []error }).Unwrap()
+	if len(errs) != 3 {
+		t.Fatalf("g.Wait() joined %d errors; want 3: %v", len(errs), err)
+	}
+	if errs[0] != errFirst {
+		t.Errorf("first joined error = %v; want %v", errs[0], errFirst)
+	}
+	for _, e := range errs[1:] {
+		if !errors.Is(e, errWindDown) || !errors.Is(e, context.Canceled) {
+			t.Errorf("joined error %v; want a canceled task's error", e)
+		}
+	}
+}
+
+func TestSyncPoint(t *testing.T) {
+	const n = 4
+	g := new(errgroup.Group)
+	p := g.NewSyncPoint(n)
+
+	// Two phases: no task starts phase 2 before all have finished phase 1.
+	var phase1 int32
+	for i := 0; i < n; i++ {
+		g.Go(func() error {
+			atomic.AddInt32(&phase1, 1)
+			if err := p.Arrive(); err != nil {
+				return err
+			}
+			if got := atomic.LoadInt32(&phase1); got != n {
+				return fmt.Errorf("task proceeded with %d of %d tasks arrived", got, n)
+			}
+			return p.Arrive()
+		})
+	}
+	if err := g.Wait(); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestSyncPointCanceled(t *testing.T) {
+	g, _ := errgroup.WithContext(context.Background())
+	p := g.NewSyncPoint(3)
+	arrived := make(chan error, 2)
+	for i := 0; i < 2; i++ {
+		g.Go(func() error {
+			err := p.Arrive()
+			arrived <- err
+			return nil
+		})
+	}
+	// The third task fails without arriving.
+	failure := errors.New("group_test: failed before the sync point")
+	g.Go(func() error { return failure })
+
+	if err := g.Wait(); err != failure {
+		t.Errorf("g.Wait() = %v; want %v", err, failure)
+	}
+	for i := 0; i < 2; i++ {
+		if err := <-arrived; err != context.Canceled {
+			t.Errorf("Arrive() = %v; want %v", err, context.Canceled)
+		}
+	}
+}
+
+func TestContext(t *testing.T) {
+	if ctx := new(errgroup.Group).Context(); ctx != context.Background() {
+		t.Errorf("zero Group Context() = %v; want context.Background()", ctx)
+	}
+
+	g, ctx := errgroup.WithContext(context.Background())
+	if g.Context() != ctx {
+		t.Fatal("Context() differs from the Context returned by WithContext")
+	}
+	g.Go(func() error { return errors.New("group_test: failed") })
+	select {
+	case <-g.Context().Done():
+	case <-time.After(5 * time.Second):
+		t.Fatal("Context() not canceled after a failure")
+	}
+	g.Wait()
+}
+
+func TestSetSoftLimit(t *testing.T) {
+	g := new(errgroup.Group)
+	var exceeded []int
+	g.SetSoftLimit(2, func(active int) {
+		exceeded = append(exceeded, active)
+	})
+
+	unblock := make(chan struct{})
+	for i := 0; i < 4; i++ {
+		g.Go(func() error {
+			<-unblock
+			return nil
+		})
+	}
+	close(unblock)
+	g.Wait()
+
+	if fmt.Sprint(exceeded) != "[3 4]" {
+		t.Errorf("onExceed called with %v; want [3 4]", exceeded)
+	}
+}
+
+func TestWaitContext(t *testing.T) {
+	g := new(errgroup.Group)
+	unblock := make(chan struct{})
+	g.Go(func() error {
+		<-unblock
+		return errors.New("group_test: late failure")
+	})
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+	start := time.Now()
+	if err := g.WaitContext(ctx); err != context.DeadlineExceeded {
+		t.Errorf("WaitContext with running tasks = %v; want %v", err, context.DeadlineExceeded)
+	}
+	if d := time.Since(start); d > 5*time.Second {
+		t.Errorf("WaitContext returned after %v; want it to return at the deadline", d)
+	}
+
+	close(unblock)
+	if err := g.WaitContext(context.Background()); err == nil {
+		t.Error("WaitContext after the tasks finished = nil; want their error")
+	}
+}
+
+func TestSetPanicHandler(t *testing.T) {
+	errTimeout := errors.New("group_test: timeout")
+	g := new(errgroup.Group)
+	g.SetRecover(true)
+	g.SetPanicHandler(func(recovered interface{}, stack []byte) error {
+		if recovered == "timeout" {
+			return errTimeout
+		}
+		return fmt.Errorf("group_test: panic %v", recovered)
+	})
+	g.Go(func() error {
+		panic("timeout")
+	})
+	if err := g.Wait(); err != errTimeout {
+		t.Errorf("g.Wait() = %v; want %v", err, errTimeout)
+	}
+}
+
+func TestAfterFunc(t *testing.T) {
+	g, _ := errgroup.WithContext(context.Background())
+	ran := make(chan string, 2)
+	g.AfterFunc(func() { ran <- "kept" })
+	stop := g.AfterFunc(func() { ran <- "stopped" })
+	if !stop() {
+		t.Error("stop() = false before cancelation; want true")
+	}
+
+	g.Go(func() error { return errors.New("group_test: failed") })
+	select {
+	case s := <-ran:
+		if s != "kept" {
+			t.Errorf("%s function ran", s)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("AfterFunc function did not run on cancelation")
+	}
+	g.Wait()
+
+	// A zero Group runs the functions at Wait.
+	var zero errgroup.Group
+	zero.AfterFunc(func() { ran <- "zero" })
+	select {
+	case s := <-ran:
+		t.Fatalf("%s function ran before Wait", s)
+	case <-time.After(10 * time.Millisecond):
+	}
+	zero.Wait()
+	if s := <-ran; s != "zero" {
+		t.Errorf("%s function ran; want zero", s)
+	}
+}
+
+func TestWithTimeout(t *testing.T) {
+	g, ctx := errgroup.WithTimeout(context.Background(), 10*time.Millisecond)
+	for i := 0; i < 3; i++ {
+		g.Go(func() error {
+			<-ctx.Done()
+			return ctx.Err()
+		})
+	}
+	if err := g.Wait(); err != errgroup.ErrBatchTimeout {
+		t.Errorf("g.Wait() = %v; want %v", err, errgroup.ErrBatchTimeout)
+	}
+	if ctx.Err() != context.DeadlineExceeded {
+		t.Errorf("ctx.Err() = %v; want %v", ctx.Err(), context.DeadlineExceeded)
+	}
+
+	// Tasks that ignore the Context still produce the timeout error.
+	g, _ = errgroup.WithTimeout(context.Background(), time.Millisecond)
+	g.Go(func() error {
+		time.Sleep(10 * time.Millisecond)
+		return nil
+	})
+	if err := g.Wait(); err != errgroup.ErrBatchTimeout {
+		t.Errorf("g.Wait() = %v; want %v", err, errgroup.ErrBatchTimeout)
+	}
+
+	// A task error before the deadline wins.
+	failure := errors.New("group_test: failed")
+	g, _ = errgroup.WithTimeout(context.Background(), time.Hour)
+	g.Go(func() error { return failure })
+	if err := g.Wait(); err != failure {
+		t.Errorf("g.Wait() = %v; want %v", err, failure)
+	}
+
+	// The timeout is not recorded as the failure of a function, even when
+	// an earlier failure did not cancel the group.
+	g, ctx = errgroup.WithTimeout(context.Background(), 10*time.Millisecond)
+	g.SetCollectAll(true)
+	g.SetCancelOnError(false)
+	g.Go(func() error { return failure })
+	g.Go(func() error {
+		<-ctx.Done()
+		return nil
+	})
+	if err := g.Wait(); err == nil || err.Error() != failure.Error() || errors.Is(err, errgroup.ErrBatchTimeout) {
+		t.Errorf("g.Wait() = %v; want only %v", err, failure)
+	}
+
+	// In collect-all mode, a timeout with no failures is still reported.
+	g, ctx = errgroup.WithTimeout(context.Background(), time.Millisecond)
+	g.SetCollectAll(true)
+	g.Go(func() error {
+		<-ctx.Done()
+		return nil
+	})
+	if err := g.Wait(); err != errgroup.ErrBatchTimeout {
+		t.Errorf("g.Wait() in collect-all mode = %v; want %v", err, errgroup.ErrBatchTimeout)
+	}
+}
+
+func TestWithContexts(t *testing.T) {
+	type key struct{}
+	request, cancelRequest := context.WithCancel(context.WithValue(context.Background(), key{}, "request"))
+	defer cancelRequest()
+	shutdown := errors.New("group_test: server shutting down")
+	server, cancelServer := context.WithCancelCause(context.Background())
+	defer cancelServer(nil)
+
+	g, ctx := errgroup.WithContexts(request, server)
+	if v := ctx.Value(key{}); v != "request" {
+		t.Errorf("ctx.Value(key) = %v; want the first parent's value", v)
+	}
+	g.Go(func() error {
+		<-ctx.Done()
+		return context.Cause(ctx)
+	})
+	select {
+	case <-ctx.Done():
+		t.Fatal("ctx canceled before any parent")
+	case <-time.After(10 * time.Millisecond):
+	}
+	cancelServer(shutdown)
+	if err := g.Wait(); err != shutdown {
+		t.Errorf("g.Wait() = %v; want %v", err, shutdown)
+	}
+
+	// A failing task cancels the Context as WithContext's does.
+	g, ctx = errgroup.WithContexts(context.Background(), context.Background())
+	failure := errors.New("group_test: failed")
+	g.Go(func() error { return failure })
+	<-ctx.Done()
+	if err := g.Wait(); err != failure {
+		t.Errorf("g.Wait() = %v; want %v", err, failure)
+	}
+}
+
+func TestLimitWaitTime(t *testing.T) {
+	g := new(errgroup.Group)
+	g.SetLimit(1)
+	const sleep = 5 * time.Millisecond
+	for i := 0; i < 3; i++ {
+		g.Go(func() error {
+			time.Sleep(sleep)
+			return nil
+		})
+	}
+	g.Wait()
+	// The second and third calls to Go each waited for a task to finish,
+	// so the third waited for a whole task.
+	if d := g.LimitWaitTime(); d < sleep {
+		t.Errorf("LimitWaitTime() = %v; want at least %v", d, sleep)
+	}
+
+	if d := new(errgroup.Group).LimitWaitTime(); d != 0 {
+		t.Errorf("LimitWaitTime() of an unused group = %v; want 0", d)
+	}
+}
+
+func TestTaskReports(t *testing.T) {
+	g := new(errgroup.Group)
+	failure := errors.New("group_test: failed")
+	g.GoNamed("slow", func() error {
+		time.Sleep(20 * time.Millisecond)
+		return nil
+	})
+	g.GoNamed("fast", func() error {
+		return failure
+	})
+	g.Wait()
+
+	reports := g.TaskReports()
+	if len(reports) != 2 {
+		t.Fatalf("TaskReports() returned %d reports; want 2", len(reports))
+	}
+	if r := reports[0]; r.Name != "fast" || r.Err != failure || r.Duration >= 20*time.Millisecond {
+		t.Errorf("first report = %+v; want the fast failure", r)
+	}
+	if r := reports[1]; r.Name != "slow" || r.Err != nil || r.Duration < 20*time.Millisecond {
+		t.Errorf("second report = %+v; want the slow success", r)
+	}
+}
+
+func TestTaskTimings(t *testing.T) {
+	g := new(errgroup.Group)
+	g.SetLimit(1)
+	const sleep = 10 * time.Millisecond
+	for _, name := range []string{"first", "second"} {
+		g.GoTimed(name, func() error {
+			time.Sleep(sleep)
+			return nil
+		})
+	}
+	g.Wait()
+
+	timings := g.TaskTimings()
+	if len(timings) != 2 {
+		t.Fatalf("TaskTimings() returned %d timings; want 2", len(timings))
+	}
+	for _, tt := range timings {
+		if tt.Start.Before(tt.Submit) || tt.End.Sub(tt.Start) < sleep {
+			t.Errorf("timing %+v is inconsistent", tt)
+		}
+	}
+	// The second task was queued behind the first.
+	second := timings[1]
+	if second.Name != "second" || second.Start.Sub(second.Submit) < sleep/2 || second.Start.Before(timings[0].End) {
+		t.Errorf("second timing = %+v; want it to start after the first ended", second)
+	}
+}
+
+func TestRetry(t *testing.T) {
+	g := new(errgroup.Group)
+	var mu sync.Mutex
+	calls := make(map[string]int)
+	task := func(name string, failures int) func() error {
+		return func() error {
+			mu.Lock()
+			defer mu.Unlock()
+			calls[name]++
+			if calls[name] <= failures {
+				return fmt.Errorf("group_test: %s failed", name)
+			}
+			return nil
+		}
+	}
+	g.GoNamed("ok", task("ok", 0))
+	g.GoNamed("flaky", task("flaky", 1))
+	g.GoNamed("flakier", task("flakier", 2))
+	if err := g.Wait(); err == nil {
+		t.Fatal("g.Wait() = nil; want an error")
+	}
+
+	if err := g.Retry(context.Background()); err == nil || !strings.Contains(err.Error(), "flakier") || strings.Contains(err.Error(), "flaky failed") {
+		t.Errorf("first g.Retry() = %v; want only the failure of flakier", err)
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	if err := g.Retry(ctx); err != context.Canceled {
+		t.Errorf("g.Retry() with a canceled Context = %v; want %v", err, context.Canceled)
+	}
+	if err := g.Retry(context.Background()); err != nil {
+		t.Errorf("last g.Retry() = %v; want nil", err)
+	}
+	want := map[string]int{"ok": 1, "flaky": 2, "flakier": 3}
+	if !reflect.DeepEqual(calls, want) {
+		t.Errorf("calls = %v; want %v", calls, want)
+	}
+	if n := len(g.TaskReports()); n != 6 {
+		t.Errorf("TaskReports() returned %d reports; want 6", n)
+	}
+}
+
+func TestRetryCanceledAfterStart(t *testing.T) {
+	g := new(errgroup.Group)
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	failed := false
+	g.GoNamed("flaky", func() error {
+		if !failed {
+			failed = true
+			return errors.New("group_test: flaky failed")
+		}
+		cancel()
+		return nil
+	})
+	if err := g.Wait(); err == nil {
+		t.Fatal("g.Wait() = nil; want an error")
+	}
+	if err := g.Retry(ctx); err != nil {
+		t.Errorf("g.Retry() canceled by a task that succeeded = %v; want nil", err)
+	}
+	if err := g.Retry(context.Background()); err != nil {
+		t.Errorf("second g.Retry() = %v; want nil", err)
+	}
+	if n := len(g.TaskReports()); n != 2 {
+		t.Errorf("len(g.TaskReports()) = %d; want 2", n)
+	}
+}
+
+func TestGoCtxWarn(t *testing.T) {
+	g, ctx := errgroup.WithContext(context.Background())
+	stale := errors.New("group_test: stale data")
+	slow := errors.New("group_test: slow backend")
+	g.GoCtxWarn(func(ctx context.Context, warn func(error)) error {
+		warn(stale)
+		warn(nil)
+		warn(slow)
+		return ctx.Err()
+	})
+	if err := g.Wait(); err != nil {
+		t.Errorf("g.Wait() = %v; want nil", err)
+	}
+	if !reflect.DeepEqual(g.Warnings(), []error{stale, slow}) {
+		t.Errorf("g.Warnings() = %v; want [%v %v]", g.Warnings(), stale, slow)
+	}
+	if err := context.Cause(ctx); err != context.Canceled {
+		t.Errorf("group canceled with cause %v; want only Wait to cancel it", err)
+	}
+}
+
+func TestFirst(t *testing.T) {
+	slow := func(ctx context.Context) (string, error) {
+		select {
+		case <-ctx.Done():
+			return "", ctx.Err()
+		case <-time.After(5 * time.Second):
+			return "slow", nil
+		}
+	}
+	fast := func(ctx context.Context) (string, error) { return "fast", nil }
+	start := time.Now()
+	if v, err := errgroup.First(context.Background(), slow, fast, slow); v != "fast" || err != nil {
+		t.Errorf("First = %q, %v; want fast, nil", v, err)
+	}
+	if d := time.Since(start); d >= 5*time.Second {
+		t.Errorf("First took %v; the slow calls were not canceled", d)
+	}
+
+	// A fast failure wins too.
+	failure := errors.New("group_test: failed")
+	failFast := func(ctx context.Context) (string, error) { return "", failure }
+	if v, err := errgroup.First(context.Background(), slow, failFast); v != "" || err != failure {
+		t.Errorf("First = %q, %v; want \"\", %v", v, err, failure)
+	}
+}
+
+func TestWaitSummary(t *testing.T) {
+	g := new(errgroup.Group)
+	g.Go(func() error { return nil })
+	if err := g.WaitSummary(); err != nil {
+		t.Fatalf("WaitSummary() of a successful batch = %v; want nil", err)
+	}
+
+	causes := []error{os.ErrNotExist, os.ErrPermission, os.ErrClosed}
+	g = new(errgroup.Group)
+	for i := 0; i < 10; i++ {
+		g.Go(func() error {
+			if i < len(causes) {
+				return causes[i]
+			}
+			return nil
+		})
+	}
+	err := g.WaitSummary()
+	var be *errgroup.BatchError
+	if !errors.As(err, &be) {
+		t.Fatalf("WaitSummary() = %v; want a *BatchError", err)
+	}
+	if want := "errgroup: batch completed with 3 of 10 tasks failing"; err.Error() != want {
+		t.Errorf("WaitSummary() = %q; want %q", err, want)
+	}
+	for _, cause := range causes {
+		if !errors.Is(err, cause) {
+			t.Errorf("WaitSummary() does not wrap %v", cause)
+		}
+	}
+	if be.TimedOut || errors.Is(err, errgroup.ErrBatchTimeout) {
+		t.Errorf("WaitSummary() = %#v; want no timeout", be)
+	}
+
+	// A timeout is reported separately from the failures.
+	g, ctx := errgroup.WithTimeout(context.Background(), time.Millisecond)
+	for i := 0; i < 3; i++ {
+		g.Go(func() error {
+			<-ctx.Done()
+			return nil
+		})
+	}
+	err = g.WaitSummary()
+	if !errors.As(err, &be) {
+		t.Fatalf("WaitSummary() after a timeout = %v; want a *BatchError", err)
+	}
+	if !be.TimedOut || be.Failed != 0 || len(be.Errs) != 0 {
+		t.Errorf("WaitSummary() after a timeout = %#v; want TimedOut and no failures", be)
+	}
+	if want := "errgroup: batch timed out with 0 of 3 tasks failing"; err.Error() != want {
+		t.Errorf("WaitSummary() = %q; want %q", err, want)
+	}
+	if !errors.Is(err, errgroup.ErrBatchTimeout) {
+		t.Errorf("WaitSummary() after a timeout does not wrap %v", errgroup.ErrBatchTimeout)
+	}
+}
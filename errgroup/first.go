@@ -0,0 +1,42 @@
+// Copyright 2026 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package errgroup
+
+import (
+	"context"
+	"sync"
+)
+
+// First calls each of fns in its own goroutine and returns the result of
+// the first call to return, whether it succeeded or failed, such as the
+// answer of whichever replica responds first.
+//
+// The Context passed to fns is derived from ctx and is canceled as soon as
+// a call returns. First waits for the other calls to return before
+// returning, and discards their results. With no fns, First returns the
+// zero value of T and nil.
+func First[T any](ctx context.Context, fns ...func(ctx context.Context) (T, error)) (T, error) {
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	var (
+		g     Group
+		once  sync.Once
+		first T
+		err   error
+	)
+	for _, fn := range fns {
+		g.Go(func() error {
+			v, e := fn(ctx)
+			once.Do(func() {
+				first, err = v, e
+				cancel()
+			})
+			return nil
+		})
+	}
+	g.Wait()
+	return first, err
+}
@@ -0,0 +1,52 @@
+// Copyright 2026 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package errgroup
+
+import (
+	"context"
+	"fmt"
+)
+
+// An IndexedError is returned by MapSlice to identify the element of the
+// input slice whose function call failed.
+type IndexedError struct {
+	Index int
+	Err   error
+}
+
+func (e *IndexedError) Error() string {
+	return fmt.Sprintf("errgroup: element %d: %v", e.Index, e.Err)
+}
+
+func (e *IndexedError) Unwrap() error {
+	return e.Err
+}
+
+// MapSlice calls f on each element of in, each call in its own goroutine,
+// and returns the results in the order of the corresponding elements.
+//
+// The Context passed to f is derived from ctx and is canceled the first time
+// a call returns a non-nil error. That error is returned by MapSlice wrapped
+// in an *IndexedError recording the index of the element that failed, along
+// with a nil slice.
+func MapSlice[T, R any](ctx context.Context, in []T, f func(context.Context, T) (R, error)) ([]R, error) {
+	g, ctx := WithContext(ctx)
+	out := make([]R, len(in))
+	for i, v := range in {
+		i, v := i, v
+		g.Go(func() error {
+			r, err := f(ctx, v)
+			if err != nil {
+				return &IndexedError{Index: i, Err: err}
+			}
+			out[i] = r
+			return nil
+		})
+	}
+	if err := g.Wait(); err != nil {
+		return nil, err
+	}
+	return out, nil
+}
@@ -0,0 +1,49 @@
+// Copyright 2026 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package errgroup_test
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"testing"
+
+	"golang.org/x/sync/errgroup"
+)
+
+func TestMapSlice(t *testing.T) {
+	double := func(_ context.Context, v int) (int, error) {
+		return 2 * v, nil
+	}
+	out, err := errgroup.MapSlice(context.Background(), []int{1, 2, 3}, double)
+	if err != nil {
+		t.Fatalf("MapSlice = %v", err)
+	}
+	if got, want := fmt.Sprint(out), "[2 4 6]"; got != want {
+		t.Errorf("MapSlice = %v; want %v", got, want)
+	}
+}
+
+func TestMapSliceIndexedError(t *testing.T) {
+	errOdd := errors.New("odd")
+	in := []int{0, 2, 4, 5, 6}
+	_, err := errgroup.MapSlice(context.Background(), in, func(_ context.Context, v int) (int, error) {
+		if v%2 != 0 {
+			return 0, errOdd
+		}
+		return v, nil
+	})
+
+	var ie *errgroup.IndexedError
+	if !errors.As(err, &ie) {
+		t.Fatalf("MapSlice error %v is not an *IndexedError", err)
+	}
+	if ie.Index != 3 {
+		t.Errorf("IndexedError.Index = %d; want 3", ie.Index)
+	}
+	if !errors.Is(err, errOdd) {
+		t.Errorf("errors.Is(%v, errOdd) = false; want true", err)
+	}
+}
@@ -0,0 +1,63 @@
+// Copyright 2026 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package errgroup
+
+import (
+	"fmt"
+	"runtime/debug"
+)
+
+// SetRecover controls whether the group recovers panics in the functions
+// passed to Go. When enabled, a panic does not crash the program: it is
+// converted into an error carrying the panic value and stack, which the group
+// then handles like any other error returned by the function.
+//
+// SetRecover must be called before any goroutine is started with Go.
+func (g *Group) SetRecover(enabled bool) {
+	g.recover = enabled
+}
+
+// SetPanicHandler sets the function that converts a panic recovered by the
+// group, when SetRecover is enabled, into the error handled by the group. It
+// is called with the recovered value and the stack trace of the panic, on
+// the goroutine that panicked. The default handler returns a *PanicError.
+// If handle returns nil, the panic is treated as a successful return.
+//
+// SetPanicHandler must be called before any goroutine is started with Go.
+// A nil handle restores the default.
+func (g *Group) SetPanicHandler(handle func(recovered interface{}, stack []byte) error) {
+	g.onPanic = handle
+}
+
+// panicError converts the value recovered from a panic into an error.
+func (g *Group) panicError(r interface{}) error {
+	if g.onPanic != nil {
+		return g.onPanic(r, debug.Stack())
+	}
+	return newPanicError(r)
+}
+
+// A PanicError is a value recovered from a panic in a function passed to Go,
+// along with the stack trace of the panic. With SetRecover enabled, the error
+// returned by Wait can be inspected with errors.As to obtain it.
+type PanicError struct {
+	Value interface{}
+	Stack []byte
+}
+
+func (p *PanicError) Error() string {
+	return fmt.Sprintf("errgroup: panic: %v\n\n%s", p.Value, p.Stack)
+}
+
+// Unwrap returns the panic value if it is an error, so that errors.Is and
+// errors.As see through a panic with an error value.
+func (p *PanicError) Unwrap() error {
+	err, _ := p.Value.(error)
+	return err
+}
+
+func newPanicError(v interface{}) error {
+	return &PanicError{Value: v, Stack: debug.Stack()}
+}
@@ -0,0 +1,140 @@
+// Copyright 2026 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package errgroup
+
+import (
+	"context"
+	"errors"
+	"time"
+)
+
+// A TaskReport records the execution of a function started with GoNamed.
+type TaskReport struct {
+	Name     string
+	Start    time.Time
+	Duration time.Duration
+	Err      error // as returned by the function
+}
+
+// A namedTask is a function started with GoNamed, kept for Retry.
+type namedTask struct {
+	name   string
+	f      func() error
+	failed bool // the last call to f did not return nil; guarded by Group.mu
+}
+
+// GoNamed is like Go, but records the execution of f under name in the
+// reports returned by TaskReports, and keeps f so that Retry can call it
+// again if it fails.
+func (g *Group) GoNamed(name string, f func() error) {
+	g.checkStrict()
+	t := &namedTask{name: name, f: f, failed: true}
+	g.mu.Lock()
+	g.named = append(g.named, t)
+	g.mu.Unlock()
+	g.Go(g.runNamed(t))
+}
+
+// runNamed returns a function that calls t.f and records its outcome.
+func (g *Group) runNamed(t *namedTask) func() error {
+	return func() error {
+		start := time.Now()
+		err := t.f()
+		r := TaskReport{Name: t.name, Start: start, Duration: time.Since(start), Err: err}
+		g.mu.Lock()
+		g.reports = append(g.reports, r)
+		t.failed = err != nil
+		g.mu.Unlock()
+		return err
+	}
+}
+
+// TaskReports returns a report for each function started with GoNamed that
+// has returned, in the order in which they returned, including the calls
+// made by Retry. A function that panicked is not reported.
+func (g *Group) TaskReports() []TaskReport {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	return append([]TaskReport(nil), g.reports...)
+}
+
+// A TaskTiming records when a function started with GoTimed was submitted,
+// started, and returned, for plotting the schedule of a batch. The time
+// between Submit and Start is spent waiting for the limit set by SetLimit.
+type TaskTiming struct {
+	Name   string
+	Submit time.Time // when GoTimed was called
+	Start  time.Time // when f was called
+	End    time.Time // when f returned
+	Err    error     // as returned by f
+}
+
+// GoTimed is like Go, but records when f is submitted, started, and returns
+// under name in the timings returned by TaskTimings.
+func (g *Group) GoTimed(name string, f func() error) {
+	submit := time.Now()
+	g.Go(func() error {
+		start := time.Now()
+		err := f()
+		tt := TaskTiming{Name: name, Submit: submit, Start: start, End: time.Now(), Err: err}
+		g.mu.Lock()
+		g.timings = append(g.timings, tt)
+		g.mu.Unlock()
+		return err
+	})
+}
+
+// TaskTimings returns the timing of each function started with GoTimed that
+// has returned, in the order in which they returned. A function that
+// panicked is not reported.
+func (g *Group) TaskTimings() []TaskTiming {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	return append([]TaskTiming(nil), g.timings...)
+}
+
+// Retry calls again each function started with GoNamed whose last call did
+// not succeed, concurrently and within the limit set by SetLimit, and waits
+// for them to return. Functions that succeeded are not called again, so
+// calling Retry until it returns nil completes a batch of idempotent tasks.
+// Retry must only be called after Wait has returned.
+//
+// Retry returns the errors of the functions it called, joined with
+// errors.Join, or nil if they all succeeded. It does not change the error
+// returned by Wait. Retry checks ctx before starting each function; once
+// ctx is done it starts no more functions, waits for those already started,
+// and returns ctx.Err() joined with their errors. If it started them all,
+// Retry reports only their errors, even if ctx is done by the time they
+// return.
+func (g *Group) Retry(ctx context.Context) error {
+	g.mu.Lock()
+	var failed []*namedTask
+	for _, t := range g.named {
+		if t.failed {
+			failed = append(failed, t)
+		}
+	}
+	g.mu.Unlock()
+
+	r := &Group{recover: g.recover, onPanic: g.onPanic, collect: true}
+	if g.sem != nil {
+		r.SetLimit(cap(g.sem))
+	}
+	var skipped error
+	for _, t := range failed {
+		if skipped = ctx.Err(); skipped != nil {
+			break
+		}
+		r.Go(g.runNamed(t))
+	}
+	err := r.Wait()
+	if skipped == nil {
+		return err
+	}
+	if err == nil {
+		return skipped
+	}
+	return errors.Join(skipped, err)
+}
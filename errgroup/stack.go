@@ -0,0 +1,59 @@
+// Copyright 2026 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package errgroup
+
+import (
+	"bytes"
+	"fmt"
+	"reflect"
+	"runtime"
+)
+
+// CaptureErrorStacks controls whether the group annotates the errors
+// returned by functions passed to Go with the stack of the goroutine at the
+// point the function returned, beginning with the function itself.
+//
+// An annotated error has the same message as the original, unwraps to it, and
+// exposes the stack through a method
+//
+//	Stack() []byte
+//
+// CaptureErrorStacks must be called before any goroutine is started with Go.
+func (g *Group) CaptureErrorStacks(enabled bool) {
+	g.stacks = enabled
+}
+
+// A stackError is an error returned by a function passed to Go, annotated
+// with the stack at which it was returned.
+type stackError struct {
+	err   error
+	stack []byte
+}
+
+func (e *stackError) Error() string { return e.err.Error() }
+func (e *stackError) Unwrap() error { return e.err }
+func (e *stackError) Stack() []byte { return e.stack }
+
+// newStackError annotates err, just returned by f, with the current stack.
+// It must be called on the goroutine that called f.
+func newStackError(err error, f func() error) error {
+	var buf bytes.Buffer
+	// f has already returned, so it is not on the stack; record it first.
+	if fn := runtime.FuncForPC(reflect.ValueOf(f).Pointer()); fn != nil {
+		file, line := fn.FileLine(fn.Entry())
+		fmt.Fprintf(&buf, "%s(...)\n\t%s:%d\n", fn.Name(), file, line)
+	}
+
+	pcs := make([]uintptr, 32)
+	frames := runtime.CallersFrames(pcs[:runtime.Callers(2, pcs)])
+	for {
+		frame, more := frames.Next()
+		fmt.Fprintf(&buf, "%s(...)\n\t%s:%d\n", frame.Function, frame.File, frame.Line)
+		if !more {
+			break
+		}
+	}
+	return &stackError{err: err, stack: buf.Bytes()}
+}
@@ -0,0 +1,68 @@
+// Copyright 2026 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package errgroup
+
+import "context"
+
+// GoStream is like g.Go, but f also produces a result, which is sent to out
+// if f succeeds. Results are sent in the order in which the calls complete.
+// A non-nil error from f is handled by the group like any other error and
+// nothing is sent.
+//
+// If the group's Context (see Group.Context) is canceled while the result
+// waits to be sent, the result is dropped. The caller must keep receiving
+// from out until Wait returns, and may close out after that.
+//
+// GoStream is a function rather than a method of Group because methods
+// cannot have type parameters.
+func GoStream[T any](g *Group, out chan<- T, f func() (T, error)) {
+	ctx := g.Context()
+	g.Go(func() error {
+		v, err := f()
+		if err != nil {
+			return err
+		}
+		select {
+		case out <- v:
+		case <-ctx.Done():
+		}
+		return nil
+	})
+}
+
+// FanOutInto calls each of producers in its own goroutine, with at most
+// limit of them running at once, and sends each successful result to sink,
+// in the order in which they complete. A limit <= 0 lets any number of
+// producers run at once; unlike with SetLimit, a limit of 0 does not mean
+// that none can run. A producer whose result cannot be sent yet blocks,
+// keeping its goroutine busy, so that a slow consumer of sink holds back the
+// producers.
+//
+// The Context passed to producers is derived from ctx and is canceled the
+// first time a producer returns a non-nil error, which FanOutInto then
+// returns; results still waiting to be sent are dropped. If ctx is done
+// while a result waits to be sent, FanOutInto returns ctx.Err(). FanOutInto
+// returns once every producer has returned, and does not close sink.
+func FanOutInto[T any](ctx context.Context, limit int, producers []func(ctx context.Context) (T, error), sink chan<- T) error {
+	g, gctx := WithContext(ctx)
+	if limit > 0 {
+		g.SetLimit(limit)
+	}
+	for _, produce := range producers {
+		g.Go(func() error {
+			v, err := produce(gctx)
+			if err != nil {
+				return err
+			}
+			select {
+			case sink <- v:
+				return nil
+			case <-gctx.Done():
+				return gctx.Err()
+			}
+		})
+	}
+	return g.Wait()
+}
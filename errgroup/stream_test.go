@@ -0,0 +1,114 @@
+// Copyright 2026 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package errgroup_test
+
+import (
+	"context"
+	"errors"
+	"reflect"
+	"runtime"
+	"sort"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"golang.org/x/sync/errgroup"
+)
+
+func TestGoStream(t *testing.T) {
+	g := new(errgroup.Group)
+	out := make(chan int)
+	for i := 0; i < 10; i++ {
+		errgroup.GoStream(g, out, func() (int, error) { return i, nil })
+	}
+	var got []int
+	done := make(chan struct{})
+	go func() {
+		for v := range out {
+			got = append(got, v)
+		}
+		close(done)
+	}()
+	if err := g.Wait(); err != nil {
+		t.Fatalf("g.Wait() = %v", err)
+	}
+	close(out)
+	<-done
+	sort.Ints(got)
+	for i, v := range got {
+		if v != i {
+			t.Fatalf("received %v; want 0 through 9", got)
+		}
+	}
+	if len(got) != 10 {
+		t.Fatalf("received %d results; want 10", len(got))
+	}
+}
+
+func TestGoStreamError(t *testing.T) {
+	g, ctx := errgroup.WithContext(context.Background())
+	out := make(chan int) // Never read: results are dropped on cancelation.
+	failure := errors.New("stream_test: failed")
+	errgroup.GoStream(g, out, func() (int, error) {
+		<-ctx.Done()
+		return 1, nil
+	})
+	errgroup.GoStream(g, out, func() (int, error) { return 0, failure })
+	if err := g.Wait(); err != failure {
+		t.Errorf("g.Wait() = %v; want %v", err, failure)
+	}
+}
+
+func TestFanOutInto(t *testing.T) {
+	const n = 5
+	var produced atomic.Int32
+	producers := make([]func(context.Context) (int, error), n)
+	for i := range producers {
+		producers[i] = func(ctx context.Context) (int, error) {
+			produced.Add(1)
+			return i, nil
+		}
+	}
+	sink := make(chan int, 1)
+	done := make(chan error)
+	go func() { done <- errgroup.FanOutInto(context.Background(), 2, producers, sink) }()
+
+	// With nobody receiving, one result fills the buffer and the producers
+	// holding the limit's two slots block sending theirs.
+	for produced.Load() < 3 {
+		runtime.Gosched()
+	}
+	time.Sleep(10 * time.Millisecond)
+	if got := produced.Load(); got != 3 {
+		t.Errorf("%d producers ran before the sink was drained; want 3", got)
+	}
+
+	var got []int
+	for len(got) < n {
+		got = append(got, <-sink)
+	}
+	if err := <-done; err != nil {
+		t.Fatalf("FanOutInto = %v; want nil", err)
+	}
+	sort.Ints(got)
+	if !reflect.DeepEqual(got, []int{0, 1, 2, 3, 4}) {
+		t.Errorf("received %v; want 0 through 4", got)
+	}
+}
+
+func TestFanOutIntoError(t *testing.T) {
+	failure := errors.New("stream_test: failed")
+	producers := []func(context.Context) (int, error){
+		func(context.Context) (int, error) { return 1, nil }, // Blocks sending.
+		func(context.Context) (int, error) { return 2, nil },
+		func(context.Context) (int, error) { return 0, failure },
+	}
+	sink := make(chan int) // Never read.
+	for _, limit := range []int{-1, 0} {
+		if err := errgroup.FanOutInto(context.Background(), limit, producers, sink); err != failure {
+			t.Errorf("FanOutInto with limit %d = %v; want %v", limit, err, failure)
+		}
+	}
+}
@@ -0,0 +1,49 @@
+// Copyright 2026 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package errgroup
+
+import "fmt"
+
+// A BatchError summarizes the failures of a group, as returned by
+// WaitSummary.
+type BatchError struct {
+	Total    int     // number of goroutines started with Go
+	Failed   int     // number of them that returned a non-nil error
+	Errs     []error // their errors, in the order in which they were returned
+	TimedOut bool    // the deadline set by WithTimeout passed
+}
+
+func (e *BatchError) Error() string {
+	if e.TimedOut {
+		return fmt.Sprintf("errgroup: batch timed out with %d of %d tasks failing", e.Failed, e.Total)
+	}
+	return fmt.Sprintf("errgroup: batch completed with %d of %d tasks failing", e.Failed, e.Total)
+}
+
+// Unwrap returns Errs, followed by ErrBatchTimeout if the batch timed out.
+func (e *BatchError) Unwrap() []error {
+	if e.TimedOut {
+		return append(e.Errs[:len(e.Errs):len(e.Errs)], ErrBatchTimeout)
+	}
+	return e.Errs
+}
+
+// WaitSummary is like Wait, but if any function passed to Go returned a
+// non-nil error, or the batch timed out, it returns a *BatchError
+// summarizing all the failures, whether or not the group is in collect-all
+// mode. The timeout is reported by TimedOut, not counted as a failure.
+func (g *Group) WaitSummary() error {
+	if g.Wait() == nil {
+		return nil
+	}
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	return &BatchError{
+		Total:    g.total,
+		Failed:   g.failures,
+		Errs:     append([]error(nil), g.errs...),
+		TimedOut: g.timedOut(),
+	}
+}
@@ -0,0 +1,68 @@
+// Copyright 2026 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package errgroup
+
+import (
+	"context"
+	"sync"
+)
+
+// A SyncPoint is a barrier at which the goroutines of a group wait for each
+// other, for computations that proceed in phases. It is reusable: once n
+// goroutines have arrived and been released, the next n arrivals form the
+// next phase.
+type SyncPoint struct {
+	ctx context.Context // nil for a zero Group
+	n   int
+
+	mu      sync.Mutex
+	arrived int           // arrivals in the current phase
+	release chan struct{} // closed when the current phase is complete
+}
+
+// NewSyncPoint returns a SyncPoint at which n goroutines must arrive before
+// any of them proceeds. Waiting at the SyncPoint is abandoned if the
+// group's Context is canceled, so that a failing goroutine that never
+// arrives does not leave the others blocked forever.
+func (g *Group) NewSyncPoint(n int) *SyncPoint {
+	return &SyncPoint{ctx: g.ctx, n: n, release: make(chan struct{})}
+}
+
+// Arrive blocks until n goroutines, including the caller, have arrived at
+// p, and then returns nil. If the group's Context is canceled first, Arrive
+// returns its error instead, and the caller no longer counts as arrived.
+func (p *SyncPoint) Arrive() error {
+	p.mu.Lock()
+	release := p.release
+	p.arrived++
+	if p.arrived >= p.n {
+		close(release)
+		p.arrived = 0
+		p.release = make(chan struct{})
+		p.mu.Unlock()
+		return nil
+	}
+	p.mu.Unlock()
+
+	var done <-chan struct{}
+	if p.ctx != nil {
+		done = p.ctx.Done()
+	}
+	select {
+	case <-release:
+		return nil
+	case <-done:
+		p.mu.Lock()
+		defer p.mu.Unlock()
+		select {
+		case <-release:
+			// The phase completed while we were giving up.
+			return nil
+		default:
+		}
+		p.arrived--
+		return p.ctx.Err()
+	}
+}
@@ -0,0 +1,37 @@
+// Copyright 2026 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package errgroup
+
+import "context"
+
+// GoCtxWarn is like Go, but calls f with the group's Context, as returned
+// by Context, and with a function warn through which f can report non-fatal
+// problems. Warnings are collected for Warnings; unlike errors returned by
+// f, they neither cancel the group nor cause Wait to return an error.
+// warn may be called concurrently, and calls with a nil error are ignored.
+func (g *Group) GoCtxWarn(f func(ctx context.Context, warn func(error)) error) {
+	ctx := g.Context()
+	g.Go(func() error {
+		return f(ctx, g.warn)
+	})
+}
+
+func (g *Group) warn(err error) {
+	if err == nil {
+		return
+	}
+	g.mu.Lock()
+	g.warnings = append(g.warnings, err)
+	g.mu.Unlock()
+}
+
+// Warnings returns the warnings reported by the functions started with
+// GoCtxWarn, in the order in which they were reported. It is meant to be
+// called after Wait has returned, when the list is complete.
+func (g *Group) Warnings() []error {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	return append([]error(nil), g.warnings...)
+}
@@ -0,0 +1,45 @@
+// Copyright 2026 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package semaphore
+
+// A MetricSink receives the gauge samples reported by a Collector.
+//
+// MetricSink decouples Collector from any particular metrics library; a
+// Prometheus collector, for instance, can implement Describe and Collect by
+// calling Collector.Collect with a MetricSink that emits constant gauges.
+type MetricSink interface {
+	Gauge(name, help string, value float64)
+}
+
+// A Collector reports the saturation of a Weighted semaphore as gauges.
+type Collector struct {
+	s                       *Weighted
+	capacity, inUse, queued string
+}
+
+// Collector returns a Collector reporting the gauges
+// namespace_capacity, namespace_in_use, and namespace_waiters for s.
+// If namespace is empty, the names are not prefixed.
+func (s *Weighted) Collector(namespace string) *Collector {
+	prefix := ""
+	if namespace != "" {
+		prefix = namespace + "_"
+	}
+	return &Collector{
+		s:        s,
+		capacity: prefix + "capacity",
+		inUse:    prefix + "in_use",
+		queued:   prefix + "waiters",
+	}
+}
+
+// Collect reports the current state of the semaphore to sink. The values are
+// read together, so they are consistent with each other.
+func (c *Collector) Collect(sink MetricSink) {
+	size, cur, waiters := c.s.snapshot()
+	sink.Gauge(c.capacity, "Maximum combined weight of the semaphore.", float64(size))
+	sink.Gauge(c.inUse, "Combined weight currently held.", float64(cur))
+	sink.Gauge(c.queued, "Number of callers waiting to acquire the semaphore.", float64(waiters))
+}
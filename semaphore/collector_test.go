@@ -0,0 +1,45 @@
+// Copyright 2026 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package semaphore_test
+
+import (
+	"context"
+	"testing"
+
+	"golang.org/x/sync/semaphore"
+)
+
+type gaugeSink map[string]float64
+
+func (s gaugeSink) Gauge(name, help string, value float64) {
+	s[name] = value
+}
+
+func TestCollector(t *testing.T) {
+	t.Parallel()
+
+	sem := semaphore.NewWeighted(4)
+	sem.Acquire(context.Background(), 3)
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	go sem.Acquire(ctx, 2)
+	waitForQueueLen(sem, 1)
+
+	sink := make(gaugeSink)
+	sem.Collector("pool").Collect(sink)
+	want := gaugeSink{
+		"pool_capacity": 4,
+		"pool_in_use":   3,
+		"pool_waiters":  1,
+	}
+	if len(sink) != len(want) {
+		t.Errorf("collected %v; want %v", sink, want)
+	}
+	for name, v := range want {
+		if sink[name] != v {
+			t.Errorf("%s = %v; want %v", name, sink[name], v)
+		}
+	}
+}
@@ -0,0 +1,19 @@
+// Copyright 2026 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package semaphore
+
+// QueueLen reports the number of callers waiting to acquire s.
+func (s *Weighted) QueueLen() int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.waiters.Len()
+}
+
+// SetSlowPathHook arranges for f to be called whenever an acquire has to
+// queue, and returns a function that removes the hook.
+func SetSlowPathHook(f func()) (restore func()) {
+	testHookSlowPath = f
+	return func() { testHookSlowPath = nil }
+}
@@ -0,0 +1,144 @@
+// Copyright 2026 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package semaphore
+
+import (
+	"context"
+	"errors"
+)
+
+// ErrIdentityCap is returned by AcquireAs when the weight requested would
+// take the weight held by the caller's identity above the cap set with
+// SetPerIdentityCap.
+var ErrIdentityCap = errors.New("semaphore: identity would exceed its cap")
+
+// SetPerIdentityCap limits the combined weight that the callers of AcquireAs
+// sharing an identity can hold, or wait for, at once to limit. A
+// limit <= 0 removes the cap, which is the default. Weight already held is
+// not affected.
+func (s *Weighted) SetPerIdentityCap(limit int64) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.identityCap = limit
+}
+
+// AcquireAs is like AcquireLease, but attributes the acquired weight to
+// identity until the lease is released. If identity would then hold more
+// than the cap set with SetPerIdentityCap, counting the weight its other
+// callers are still waiting for, AcquireAs returns ErrIdentityCap at once
+// without waiting.
+//
+// If identity has a class reserve (see ReserveClass), the weight is taken
+// from the unused part of the reserve first, and only the remainder is
+// acquired from the semaphore.
+func (s *Weighted) AcquireAs(ctx context.Context, identity string, n int64) (*Lease, error) {
+	s.mu.Lock()
+	held := s.identities[identity]
+	if s.identityCap > 0 && held+n > s.identityCap {
+		s.mu.Unlock()
+		return nil, ErrIdentityCap
+	}
+	if s.identities == nil {
+		s.identities = make(map[string]int64)
+	}
+	// Count n against identity while waiting, so that concurrent callers
+	// cannot exceed the cap together.
+	s.identities[identity] = held + n
+	var reserved int64
+	if r := s.classes[identity]; r != nil {
+		reserved = min(r.free, n)
+		r.free -= reserved
+	}
+	s.mu.Unlock()
+
+	if reserved < n {
+		if err := s.Acquire(ctx, n-reserved); err != nil {
+			s.mu.Lock()
+			s.unholdIdentity(identity, n)
+			excess := s.returnReserve(identity, reserved)
+			s.mu.Unlock()
+			if excess > 0 {
+				s.Release(excess)
+			}
+			return nil, err
+		}
+	}
+	return &Lease{s: s, n: n, identity: identity, as: true, reserved: reserved}, nil
+}
+
+// unholdIdentity stops attributing a weight of n to identity.
+// s.mu must be held.
+func (s *Weighted) unholdIdentity(identity string, n int64) {
+	if held := s.identities[identity] - n; held > 0 {
+		s.identities[identity] = held
+	} else {
+		delete(s.identities, identity)
+	}
+}
+
+// classReserve is the weight set aside for a class by ReserveClass.
+type classReserve struct {
+	min  int64 // Weight the class is guaranteed.
+	held int64 // Weight acquired from the semaphore for the reserve.
+	free int64 // Part of held not lent to a Lease.
+}
+
+// ReserveClass guarantees the callers of AcquireAs using class as their
+// identity a combined weight of reserve, even while other callers hold all
+// the remaining capacity of the semaphore. The reserve is acquired from
+// the semaphore at once, without blocking, and counts as held until it is
+// lowered; ReserveClass reports false, and leaves any existing reserve of
+// class unchanged, if the weight needed to raise it is not available.
+//
+// Lowering a reserve releases its unused weight immediately; weight that
+// is lent to the class's leases is released as they are. A reserve <= 0
+// removes it.
+func (s *Weighted) ReserveClass(class string, reserve int64) bool {
+	s.mu.Lock()
+	r := s.classes[class]
+	if r == nil {
+		r = new(classReserve)
+	}
+	if grow := reserve - r.held; grow > 0 {
+		if s.size-s.cur < grow || s.waiters.Len() != 0 {
+			s.mu.Unlock()
+			return false
+		}
+		s.account()
+		s.cur += grow
+		s.acquired()
+		r.held += grow
+		r.free += grow
+	}
+	if s.classes == nil {
+		s.classes = make(map[string]*classReserve)
+	}
+	s.classes[class] = r
+	r.min = max(reserve, 0)
+	excess := s.returnReserve(class, 0)
+	s.mu.Unlock()
+	if excess > 0 {
+		s.Release(excess)
+	}
+	return true
+}
+
+// returnReserve gives a weight of n lent to a Lease back to the reserve of
+// class, and returns the weight the reserve now holds beyond its minimum,
+// which the caller must release. s.mu must be held.
+func (s *Weighted) returnReserve(class string, n int64) int64 {
+	r := s.classes[class]
+	if r == nil {
+		return 0
+	}
+	r.free += n
+	excess := max(min(r.free, r.held-r.min), 0)
+	r.free -= excess
+	r.held -= excess
+	if r.held == 0 {
+		delete(s.classes, class)
+	}
+	return excess
+}
@@ -0,0 +1,119 @@
+// Copyright 2026 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package semaphore_test
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"golang.org/x/sync/semaphore"
+)
+
+func TestWeightedPerIdentityCap(t *testing.T) {
+	t.Parallel()
+
+	ctx := context.Background()
+	sem := semaphore.NewWeighted(10)
+	sem.SetPerIdentityCap(3)
+
+	a1, err := sem.AcquireAs(ctx, "alice", 2)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := sem.AcquireAs(ctx, "alice", 2); err != semaphore.ErrIdentityCap {
+		t.Errorf("AcquireAs beyond the cap = %v; want %v", err, semaphore.ErrIdentityCap)
+	}
+	a2, err := sem.AcquireAs(ctx, "alice", 1)
+	if err != nil {
+		t.Errorf("AcquireAs up to the cap = %v; want nil", err)
+	}
+	b, err := sem.AcquireAs(ctx, "bob", 3)
+	if err != nil {
+		t.Errorf("AcquireAs for another identity = %v; want nil", err)
+	}
+
+	// Releasing a lease makes room for its identity again.
+	a1.Release()
+	a1.Release() // No effect.
+	a3, err := sem.AcquireAs(ctx, "alice", 2)
+	if err != nil {
+		t.Errorf("AcquireAs after a release = %v; want nil", err)
+	}
+
+	a2.Release()
+	a3.Release()
+	b.Release()
+
+	// A failed acquisition does not count against the identity.
+	cctx, cancel := context.WithCancel(ctx)
+	cancel()
+	sem.MustAcquire(ctx, 10) // Leaves no tokens.
+	if _, err := sem.AcquireAs(cctx, "carol", 3); err != context.Canceled {
+		t.Fatalf("AcquireAs with a canceled Context = %v; want %v", err, context.Canceled)
+	}
+	sem.Release(10)
+	if _, err := sem.AcquireAs(ctx, "carol", 3); err != nil {
+		t.Errorf("AcquireAs after a failed acquisition = %v; want nil", err)
+	}
+}
+
+func TestReserveClass(t *testing.T) {
+	t.Parallel()
+
+	ctx := context.Background()
+	sem := semaphore.NewWeighted(10)
+	if !sem.ReserveClass("gold", 3) {
+		t.Fatal("ReserveClass(gold, 3) = false; want true")
+	}
+	if sem.ReserveClass("silver", 8) {
+		t.Fatal("ReserveClass(silver, 8) = true with only 7 tokens unreserved; want false")
+	}
+
+	// Another class exhausts the general pool.
+	bulk, err := sem.AcquireAs(ctx, "bulk", 7)
+	if err != nil {
+		t.Fatalf("AcquireAs(bulk, 7) = %v; want nil", err)
+	}
+	if sem.TryAcquire(1) {
+		t.Fatal("TryAcquire(1) succeeded with the general pool exhausted")
+	}
+
+	// The reserved class can still acquire up to its reserve, but no more.
+	gold, err := sem.AcquireAs(ctx, "gold", 3)
+	if err != nil {
+		t.Fatalf("AcquireAs(gold, 3) with the general pool exhausted = %v; want nil", err)
+	}
+	tctx, cancel := context.WithTimeout(ctx, 10*time.Millisecond)
+	defer cancel()
+	if _, err := sem.AcquireAs(tctx, "gold", 1); err != context.DeadlineExceeded {
+		t.Fatalf("AcquireAs(gold, 1) beyond the reserve = %v; want %v", err, context.DeadlineExceeded)
+	}
+
+	// Released weight returns to the reserve, not to the general pool.
+	gold.Release()
+	if sem.TryAcquire(1) {
+		t.Fatal("TryAcquire(1) took weight returned to the reserve")
+	}
+	gold, err = sem.AcquireAs(ctx, "gold", 2)
+	if err != nil {
+		t.Fatalf("AcquireAs(gold, 2) after a release = %v; want nil", err)
+	}
+
+	// Lowering the reserve releases its unused weight at once, and the
+	// weight still lent to gold once it is released.
+	bulk.Release()
+	if !sem.ReserveClass("gold", 0) {
+		t.Fatal("ReserveClass(gold, 0) = false; want true")
+	}
+	if !sem.TryAcquire(8) {
+		t.Fatal("TryAcquire(8) failed after lowering the reserve")
+	}
+	gold.Release()
+	if !sem.TryAcquire(2) {
+		t.Fatal("TryAcquire(2) failed after releasing the last reserved weight")
+	}
+	sem.Release(10)
+}
@@ -0,0 +1,71 @@
+// Copyright 2026 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package semaphore
+
+import (
+	"container/list"
+	"encoding/json"
+	"time"
+)
+
+// jsonState is the JSON form of a Weighted semaphore; see MarshalJSON.
+type jsonState struct {
+	Size      int64        `json:"size"`
+	Cur       int64        `json:"cur"`
+	Available int64        `json:"available"`
+	Waiters   int          `json:"waiters"`
+	Details   []jsonWaiter `json:"waiter_details,omitempty"`
+}
+
+type jsonWaiter struct {
+	N        int64  `json:"n"`
+	Priority int    `json:"priority"`
+	Waited   string `json:"waited"`
+	Parked   bool   `json:"parked,omitempty"`
+}
+
+// SetDebug controls whether MarshalJSON describes each waiter, rather than
+// only counting them. Describing the waiters holds the semaphore's lock for
+// time proportional to their number, so it is disabled by default.
+func (s *Weighted) SetDebug(enabled bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.debug = enabled
+}
+
+// MarshalJSON implements json.Marshaler, so that a semaphore can be served
+// as is by a debugging endpoint. It encodes an object with the size of s,
+// the weight currently held (cur), the weight that can be acquired without
+// blocking (available), and the number of waiters, all read at the same
+// instant. If SetDebug is enabled, the object also lists the waiters, in
+// the order in which they arrived within the queue and then the set of
+// waiters too large for the current size, under waiter_details.
+func (s *Weighted) MarshalJSON() ([]byte, error) {
+	s.mu.Lock()
+	st := jsonState{
+		Size:    s.size,
+		Cur:     s.cur,
+		Waiters: s.waiters.Len() + s.parked.Len(),
+	}
+	if s.size > s.cur {
+		st.Available = s.size - s.cur
+	}
+	if s.debug {
+		now := time.Now()
+		for _, l := range []*list.List{&s.waiters, &s.parked} {
+			for e := l.Front(); e != nil; e = e.Next() {
+				w := e.Value.(*waiter)
+				st.Details = append(st.Details, jsonWaiter{
+					N:        w.n,
+					Priority: w.priority,
+					Waited:   now.Sub(w.since).String(),
+					Parked:   w.parked,
+				})
+			}
+		}
+	}
+	s.mu.Unlock()
+	return json.Marshal(st)
+}
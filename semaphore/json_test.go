@@ -0,0 +1,61 @@
+// Copyright 2026 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package semaphore_test
+
+import (
+	"context"
+	"encoding/json"
+	"testing"
+
+	"golang.org/x/sync/semaphore"
+)
+
+func TestWeightedMarshalJSON(t *testing.T) {
+	t.Parallel()
+
+	sem := semaphore.NewWeighted(4)
+	sem.Acquire(context.Background(), 3)
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	go sem.AcquirePriority(ctx, 2, 5)
+	waitForQueueLen(sem, 1)
+
+	type waiter struct {
+		N        int64
+		Priority int
+		Waited   string
+	}
+	var got struct {
+		Size, Cur, Available int64
+		Waiters              int
+		Details              []waiter `json:"waiter_details"`
+	}
+	b, err := json.Marshal(sem)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := json.Unmarshal(b, &got); err != nil {
+		t.Fatal(err)
+	}
+	if got.Size != 4 || got.Cur != 3 || got.Available != 1 || got.Waiters != 1 {
+		t.Errorf("json.Marshal(sem) = %s; want size 4, cur 3, available 1, waiters 1", b)
+	}
+	if got.Details != nil {
+		t.Errorf("json.Marshal(sem) without SetDebug described the waiters: %s", b)
+	}
+
+	sem.SetDebug(true)
+	b, err = json.Marshal(sem)
+	if err != nil {
+		t.Fatal(err)
+	}
+	got.Details = nil
+	if err := json.Unmarshal(b, &got); err != nil {
+		t.Fatal(err)
+	}
+	if len(got.Details) != 1 || got.Details[0].N != 2 || got.Details[0].Priority != 5 || got.Details[0].Waited == "" {
+		t.Errorf("json.Marshal(sem) with SetDebug = %s; want one waiter for 2 at priority 5", b)
+	}
+}
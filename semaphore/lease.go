@@ -0,0 +1,78 @@
+// Copyright 2026 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package semaphore
+
+import (
+	"context"
+	"iter"
+	"sync/atomic"
+)
+
+// A Lease is a weight acquired from a Weighted semaphore that is given back
+// by calling its Release method.
+type Lease struct {
+	s        *Weighted
+	n        int64
+	released atomic.Bool
+
+	identity string // See AcquireAs.
+	as       bool   // Acquired with AcquireAs.
+	reserved int64  // Weight taken from the identity's class reserve.
+}
+
+// AcquireLease is like Acquire, but returns the acquired weight as a Lease.
+func (s *Weighted) AcquireLease(ctx context.Context, n int64) (*Lease, error) {
+	if err := s.Acquire(ctx, n); err != nil {
+		return nil, err
+	}
+	return &Lease{s: s, n: n}, nil
+}
+
+// Weight returns the weight held by l.
+func (l *Lease) Weight() int64 {
+	return l.n
+}
+
+// Release releases the weight held by l. Calls after the first have no
+// effect.
+func (l *Lease) Release() {
+	if l.released.CompareAndSwap(false, true) {
+		if l.as {
+			l.s.mu.Lock()
+			l.s.unholdIdentity(l.identity, l.n)
+			n := l.n - l.reserved + l.s.returnReserve(l.identity, l.reserved)
+			l.s.mu.Unlock()
+			if n > 0 {
+				l.s.Release(n)
+			}
+			return
+		}
+		l.s.Release(l.n)
+	}
+}
+
+// Leases returns an iterator that acquires count leases of weight n, one at a
+// time, yielding each to the loop body and releasing it when the body
+// finishes the iteration, even if the body breaks out of the loop. The body
+// may release a lease early.
+//
+// If an acquisition fails, the iterator yields a nil Lease with the error
+// and stops.
+func (s *Weighted) Leases(ctx context.Context, n int64, count int) iter.Seq2[*Lease, error] {
+	return func(yield func(*Lease, error) bool) {
+		for i := 0; i < count; i++ {
+			l, err := s.AcquireLease(ctx, n)
+			if err != nil {
+				yield(nil, err)
+				return
+			}
+			more := yield(l, nil)
+			l.Release()
+			if !more {
+				return
+			}
+		}
+	}
+}
@@ -0,0 +1,69 @@
+// Copyright 2026 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package semaphore_test
+
+import (
+	"context"
+	"testing"
+
+	"golang.org/x/sync/semaphore"
+)
+
+func TestWeightedLeases(t *testing.T) {
+	t.Parallel()
+
+	ctx := context.Background()
+	sem := semaphore.NewWeighted(3)
+	iterations := 0
+	for l, err := range sem.Leases(ctx, 2, 5) {
+		if err != nil {
+			t.Fatalf("Leases yielded %v", err)
+		}
+		iterations++
+		if l.Weight() != 2 {
+			t.Errorf("Lease.Weight() = %d; want 2", l.Weight())
+		}
+		// The lease holds 2 of 3 tokens for the duration of the iteration.
+		if sem.TryAcquire(2) {
+			t.Fatal("TryAcquire(2) succeeded while a lease of 2 was held")
+		}
+		if iterations == 3 {
+			l.Release() // Releasing early is fine.
+			if !sem.TryAcquire(3) {
+				t.Fatal("TryAcquire(3) failed after releasing the lease early")
+			}
+			sem.Release(3)
+		}
+	}
+	if iterations != 5 {
+		t.Errorf("Leases yielded %d leases; want 5", iterations)
+	}
+	if !sem.TryAcquire(3) {
+		t.Fatal("leases not released after the loop")
+	}
+	sem.Release(3)
+
+	// Breaking out of the loop releases the current lease.
+	for range sem.Leases(ctx, 3, 5) {
+		break
+	}
+	if !sem.TryAcquire(3) {
+		t.Fatal("lease not released after break")
+	}
+
+	// A failed acquisition ends the iteration with its error.
+	cctx, cancel := context.WithCancel(ctx)
+	cancel()
+	var errs []error
+	for l, err := range sem.Leases(cctx, 1, 5) {
+		if l != nil {
+			t.Error("Leases yielded a lease from a full semaphore")
+		}
+		errs = append(errs, err)
+	}
+	if len(errs) != 1 || errs[0] != context.Canceled {
+		t.Errorf("Leases with a canceled ctx yielded %v; want [%v]", errs, context.Canceled)
+	}
+}
@@ -0,0 +1,47 @@
+// Copyright 2026 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package semaphore
+
+import "context"
+
+// Acquire1 is shorthand for Acquire(ctx, 1).
+func (s *Weighted) Acquire1(ctx context.Context) error {
+	return s.Acquire(ctx, 1)
+}
+
+// Release1 is shorthand for Release(1).
+func (s *Weighted) Release1() {
+	s.Release(1)
+}
+
+// A Lite is a counting semaphore: a Weighted semaphore whose every
+// acquisition has a weight of 1, for the common case of bounding the number
+// of concurrent operations.
+type Lite struct {
+	s *Weighted
+}
+
+// NewLite creates a counting semaphore admitting up to n holders at once.
+func NewLite(n int64) *Lite {
+	return &Lite{s: NewWeighted(n)}
+}
+
+// Acquire acquires the semaphore, blocking until it is available or ctx is
+// done. On success, returns nil. On failure, returns ctx.Err() and leaves
+// the semaphore unchanged.
+func (l *Lite) Acquire(ctx context.Context) error {
+	return l.s.Acquire(ctx, 1)
+}
+
+// TryAcquire acquires the semaphore without blocking. On success, returns
+// true. On failure, returns false and leaves the semaphore unchanged.
+func (l *Lite) TryAcquire() bool {
+	return l.s.TryAcquire(1)
+}
+
+// Release releases the semaphore.
+func (l *Lite) Release() {
+	l.s.Release(1)
+}
@@ -0,0 +1,62 @@
+// Copyright 2026 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package semaphore_test
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"golang.org/x/sync/semaphore"
+)
+
+func TestLite(t *testing.T) {
+	t.Parallel()
+
+	ctx := context.Background()
+	const n = 3
+	l := semaphore.NewLite(n)
+	if err := l.Acquire(ctx); err != nil {
+		t.Fatal(err)
+	}
+	for i := 1; i < n; i++ {
+		if !l.TryAcquire() {
+			t.Fatalf("TryAcquire #%d failed below capacity", i)
+		}
+	}
+	if l.TryAcquire() {
+		t.Fatal("TryAcquire succeeded at capacity")
+	}
+	cctx, cancel := context.WithTimeout(ctx, 10*time.Millisecond)
+	defer cancel()
+	if err := l.Acquire(cctx); err != context.DeadlineExceeded {
+		t.Fatalf("Acquire at capacity = %v; want %v", err, context.DeadlineExceeded)
+	}
+
+	for i := 0; i < n; i++ {
+		l.Release()
+	}
+	for i := 0; i < n; i++ {
+		if !l.TryAcquire() {
+			t.Fatalf("TryAcquire #%d failed after releasing everything", i)
+		}
+	}
+}
+
+func TestWeightedAcquire1(t *testing.T) {
+	t.Parallel()
+
+	sem := semaphore.NewWeighted(1)
+	if err := sem.Acquire1(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	if sem.TryAcquire(1) {
+		t.Error("TryAcquire(1) succeeded after Acquire1")
+	}
+	sem.Release1()
+	if !sem.TryAcquire(1) {
+		t.Error("TryAcquire(1) failed after Release1")
+	}
+}
@@ -0,0 +1,159 @@
+// Copyright 2026 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package semaphore
+
+import (
+	"container/list"
+	"context"
+	"sync"
+	"time"
+)
+
+// RefillWeighted is a weighted semaphore whose tokens are not returned by
+// the callers that acquired them but accrue over time at a fixed rate, up to
+// its capacity, making it a weighted rate limiter. Like Weighted, it serves
+// waiting callers in the order in which they arrived, so a large request is
+// not starved by a steady stream of small ones.
+type RefillWeighted struct {
+	capacity int64
+	every    time.Duration // time for one token to accrue
+
+	mu      sync.Mutex
+	tokens  float64   // available tokens as of last
+	last    time.Time // when tokens was last brought up to date
+	waiters list.List // of *waiter
+	timer   *time.Timer
+}
+
+// NewRefillWeighted creates a new RefillWeighted that starts full with
+// capacity tokens and accrues one more token every d, up to capacity.
+// A d <= 0 refills the semaphore instantly.
+func NewRefillWeighted(capacity int64, d time.Duration) *RefillWeighted {
+	return &RefillWeighted{
+		capacity: capacity,
+		every:    d,
+		tokens:   float64(capacity),
+		last:     time.Now(),
+	}
+}
+
+// Acquire acquires n tokens, blocking until they have accrued or ctx is
+// done. On success, returns nil. On failure, returns ctx.Err() and leaves
+// the semaphore unchanged.
+//
+// If n exceeds the capacity of the semaphore, Acquire returns
+// ErrWeightTooLarge immediately, as that many tokens can never accrue.
+func (s *RefillWeighted) Acquire(ctx context.Context, n int64) error {
+	if n > s.capacity {
+		return ErrWeightTooLarge
+	}
+
+	s.mu.Lock()
+	s.refill()
+	if s.waiters.Len() == 0 && s.tokens >= float64(n) {
+		s.tokens -= float64(n)
+		s.mu.Unlock()
+		return nil
+	}
+
+	ready := make(chan struct{})
+	w := &waiter{n: n, ready: ready}
+	w.elem = s.waiters.PushBack(w)
+	s.schedule()
+	s.mu.Unlock()
+
+	select {
+	case <-ctx.Done():
+		s.mu.Lock()
+		defer s.mu.Unlock()
+		select {
+		case <-ready:
+			// Acquired the tokens after we stopped waiting.
+			return nil
+		default:
+		}
+		isFront := s.waiters.Front() == w.elem
+		s.waiters.Remove(w.elem)
+		if isFront {
+			// The next waiter may need fewer tokens than w did.
+			s.notifyWaiters()
+		}
+		return ctx.Err()
+
+	case <-ready:
+		return nil
+	}
+}
+
+// TryAcquire acquires n tokens without blocking.
+// On success, returns true. On failure, returns false and leaves the semaphore unchanged.
+func (s *RefillWeighted) TryAcquire(n int64) bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.refill()
+	if s.waiters.Len() == 0 && s.tokens >= float64(n) {
+		s.tokens -= float64(n)
+		return true
+	}
+	return false
+}
+
+// refill brings s.tokens up to date.
+// s.mu must be held.
+func (s *RefillWeighted) refill() {
+	now := time.Now()
+	s.tokens += float64(now.Sub(s.last)) / float64(s.every)
+	if s.every <= 0 || s.tokens > float64(s.capacity) {
+		s.tokens = float64(s.capacity)
+	}
+	s.last = now
+}
+
+// notifyWaiters serves the waiters at the front of the queue that the
+// accrued tokens satisfy, and schedules the next refill if any remain.
+// s.mu must be held.
+func (s *RefillWeighted) notifyWaiters() {
+	s.refill()
+	for {
+		next := s.waiters.Front()
+		if next == nil {
+			break
+		}
+		w := next.Value.(*waiter)
+		if s.tokens < float64(w.n) {
+			// As in Weighted, don't let smaller requests behind w barge
+			// ahead of it.
+			break
+		}
+		s.tokens -= float64(w.n)
+		s.waiters.Remove(next)
+		close(w.ready)
+	}
+	s.schedule()
+}
+
+// schedule arranges for notifyWaiters to run once enough tokens have accrued
+// for the waiter at the front of the queue.
+// s.mu must be held.
+func (s *RefillWeighted) schedule() {
+	if s.timer != nil {
+		s.timer.Stop()
+		s.timer = nil
+	}
+	next := s.waiters.Front()
+	if next == nil {
+		return
+	}
+	need := float64(next.Value.(*waiter).n) - s.tokens
+	d := time.Duration(need * float64(s.every))
+	if d <= 0 {
+		d = time.Nanosecond
+	}
+	s.timer = time.AfterFunc(d, func() {
+		s.mu.Lock()
+		defer s.mu.Unlock()
+		s.notifyWaiters()
+	})
+}
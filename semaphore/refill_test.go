@@ -0,0 +1,87 @@
+// Copyright 2026 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package semaphore_test
+
+import (
+	"context"
+	"sync"
+	"testing"
+	"time"
+
+	"golang.org/x/sync/semaphore"
+)
+
+func TestRefillWeighted(t *testing.T) {
+	t.Parallel()
+
+	ctx := context.Background()
+	const every = time.Millisecond
+	sem := semaphore.NewRefillWeighted(10, every)
+
+	// The semaphore starts full.
+	start := time.Now()
+	if err := sem.Acquire(ctx, 10); err != nil {
+		t.Fatalf("Acquire(10) = %v", err)
+	}
+	if sem.TryAcquire(5) {
+		t.Fatal("TryAcquire(5) succeeded right after draining the semaphore")
+	}
+
+	// Tokens accrue one per millisecond.
+	if err := sem.Acquire(ctx, 5); err != nil {
+		t.Fatalf("Acquire(5) = %v", err)
+	}
+	if d := time.Since(start); d < 5*every {
+		t.Errorf("Acquire(5) on an empty semaphore returned after %v; want at least %v", d, 5*every)
+	}
+
+	if err := sem.Acquire(ctx, 11); err != semaphore.ErrWeightTooLarge {
+		t.Errorf("Acquire(11) = %v; want %v", err, semaphore.ErrWeightTooLarge)
+	}
+
+	cctx, cancel := context.WithTimeout(ctx, every)
+	defer cancel()
+	if err := sem.Acquire(cctx, 10); err != context.DeadlineExceeded {
+		t.Errorf("Acquire(10) with a short deadline = %v; want %v", err, context.DeadlineExceeded)
+	}
+}
+
+func TestRefillWeightedNoStarvation(t *testing.T) {
+	t.Parallel()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	const every = 100 * time.Microsecond
+	sem := semaphore.NewRefillWeighted(10, every)
+	sem.Acquire(ctx, 10)
+
+	// A steady stream of small requests would always find a token if they
+	// could barge ahead of the large one.
+	var wg sync.WaitGroup
+	for i := 0; i < 4; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for sem.Acquire(ctx, 1) == nil {
+			}
+		}()
+	}
+	defer func() {
+		cancel()
+		wg.Wait()
+	}()
+
+	time.Sleep(time.Millisecond)
+	done := make(chan struct{})
+	go func() {
+		sem.Acquire(ctx, 10)
+		close(done)
+	}()
+	select {
+	case <-done:
+	case <-time.After(5 * time.Second):
+		t.Fatal("large Acquire starved by small ones")
+	}
+}
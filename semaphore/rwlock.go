@@ -0,0 +1,85 @@
+// Copyright 2026 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package semaphore
+
+import (
+	"context"
+	"errors"
+	"sync"
+)
+
+// ErrUpgradeConflict is returned by RWLock.Upgrade when another reader is
+// already upgrading its lock.
+var ErrUpgradeConflict = errors.New("semaphore: another reader is upgrading its lock")
+
+// An RWLock is a reader/writer lock built on a Weighted semaphore of size
+// maxReaders: a reader acquires a weight of 1 and a writer the whole
+// semaphore, so writers are not starved by a stream of readers. Unlike
+// sync.RWMutex, its acquisitions can be canceled and a read lock can be
+// upgraded to a write lock.
+type RWLock struct {
+	s   *Weighted
+	max int64
+
+	mu        sync.Mutex
+	upgrading bool
+}
+
+// NewRWLock returns an RWLock admitting up to maxReaders readers at once.
+func NewRWLock(maxReaders int64) *RWLock {
+	return &RWLock{s: NewWeighted(maxReaders), max: maxReaders}
+}
+
+// RLock locks l for reading, blocking until it can or ctx is done.
+// On failure, it returns ctx.Err() and leaves l unchanged.
+func (l *RWLock) RLock(ctx context.Context) error {
+	return l.s.Acquire(ctx, 1)
+}
+
+// RUnlock undoes a single RLock call.
+func (l *RWLock) RUnlock() {
+	l.s.Release(1)
+}
+
+// Lock locks l for writing, blocking until it can or ctx is done.
+// On failure, it returns ctx.Err() and leaves l unchanged.
+func (l *RWLock) Lock(ctx context.Context) error {
+	return l.s.Acquire(ctx, l.max)
+}
+
+// Unlock unlocks l for writing, after Lock or a successful Upgrade.
+func (l *RWLock) Unlock() {
+	l.s.Release(l.max)
+}
+
+// Upgrade turns the read lock held by the caller into a write lock, without
+// unlocking l in between, so that no other writer can lock l first. It
+// blocks until the other readers have unlocked l, ahead of any writer or
+// reader already waiting.
+//
+// Two readers upgrading at once would each wait forever for the other to
+// unlock, so if another reader is already upgrading, Upgrade returns
+// ErrUpgradeConflict at once. On this or any other failure, such as ctx
+// being done, the caller still holds its read lock; a caller that lost an
+// upgrade conflict must unlock it before the other upgrade can complete.
+func (l *RWLock) Upgrade(ctx context.Context) error {
+	l.mu.Lock()
+	if l.upgrading {
+		l.mu.Unlock()
+		return ErrUpgradeConflict
+	}
+	l.upgrading = true
+	l.mu.Unlock()
+
+	// Readers and writers acquire with priority 0, so the upgrade overtakes
+	// any of them waiting; a writer queued ahead of it could otherwise wait
+	// for the caller's read lock forever, and the caller for the writer.
+	err := l.s.AcquirePriority(ctx, l.max-1, 1)
+
+	l.mu.Lock()
+	l.upgrading = false
+	l.mu.Unlock()
+	return err
+}
@@ -0,0 +1,90 @@
+// Copyright 2026 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package semaphore_test
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"golang.org/x/sync/semaphore"
+)
+
+func TestRWLockUpgrade(t *testing.T) {
+	t.Parallel()
+
+	ctx := context.Background()
+	l := semaphore.NewRWLock(4)
+	l.RLock(ctx)
+	l.RLock(ctx) // Another reader.
+
+	// A writer queued before the upgrade must not get in first.
+	var order []string
+	writerDone := make(chan struct{})
+	go func() {
+		l.Lock(ctx)
+		order = append(order, "writer")
+		l.Unlock()
+		close(writerDone)
+	}()
+	time.Sleep(10 * time.Millisecond)
+
+	upgraded := make(chan error)
+	go func() { upgraded <- l.Upgrade(ctx) }()
+	select {
+	case err := <-upgraded:
+		t.Fatalf("Upgrade returned %v while another reader held the lock", err)
+	case <-time.After(10 * time.Millisecond):
+	}
+
+	l.RUnlock() // The other reader leaves.
+	if err := <-upgraded; err != nil {
+		t.Fatalf("Upgrade = %v; want nil", err)
+	}
+	order = append(order, "upgrader")
+	l.Unlock()
+	<-writerDone
+	if len(order) != 2 || order[0] != "upgrader" {
+		t.Errorf("locked for writing in order %v; want the upgrader first", order)
+	}
+}
+
+func TestRWLockUpgradeConflict(t *testing.T) {
+	t.Parallel()
+
+	ctx := context.Background()
+	l := semaphore.NewRWLock(2)
+	l.RLock(ctx)
+	l.RLock(ctx)
+
+	upgraded := make(chan error)
+	go func() { upgraded <- l.Upgrade(ctx) }()
+	time.Sleep(10 * time.Millisecond)
+
+	// The second upgrade would deadlock with the first; it must fail, and
+	// the first complete once the loser gives up its read lock.
+	if err := l.Upgrade(ctx); err != semaphore.ErrUpgradeConflict {
+		t.Fatalf("second Upgrade = %v; want %v", err, semaphore.ErrUpgradeConflict)
+	}
+	l.RUnlock()
+	if err := <-upgraded; err != nil {
+		t.Fatalf("first Upgrade = %v; want nil", err)
+	}
+	l.Unlock()
+
+	// A canceled upgrade leaves the read lock held and allows another.
+	l.RLock(ctx)
+	l.RLock(ctx)
+	cctx, cancel := context.WithTimeout(ctx, 10*time.Millisecond)
+	defer cancel()
+	if err := l.Upgrade(cctx); err != context.DeadlineExceeded {
+		t.Fatalf("Upgrade past the deadline = %v; want %v", err, context.DeadlineExceeded)
+	}
+	l.RUnlock()
+	if err := l.Upgrade(ctx); err != nil {
+		t.Errorf("Upgrade after a canceled one = %v; want nil", err)
+	}
+	l.Unlock()
+}
@@ -0,0 +1,71 @@
+// Copyright 2026 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package semaphore
+
+// A Scheduler is a policy for choosing which waiters to serve when tokens
+// become available; see SetScheduler.
+type Scheduler int
+
+const (
+	// FIFO serves waiters in order of priority and then of arrival, as
+	// described by AcquirePriority and SetAging. A waiter that does not fit
+	// holds back the waiters behind it, so that large requests are not
+	// starved by a stream of small ones. It is the default.
+	FIFO Scheduler = iota
+
+	// EDF (earliest deadline first) serves, among the waiters that fit,
+	// the one whose Context has the earliest deadline, and waiters whose
+	// Context has no deadline after all others, in order of arrival.
+	// Priorities are ignored. Since a waiter that does not fit never holds
+	// back the others, large requests can be starved by small ones; EDF
+	// suits waiters that give up when their deadline passes.
+	EDF
+)
+
+// SetScheduler sets the policy for choosing which waiters to serve when
+// tokens become available. The policy applies to the waiters already queued
+// as well as to later ones.
+func (s *Weighted) SetScheduler(sched Scheduler) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.sched = sched
+	s.blocked = 0
+	// Under the new policy, a queued waiter may now be served.
+	s.notifyWaiters()
+}
+
+// notifyEDF serves queued waiters as long as any of them fits, in earliest
+// deadline first order.
+// s.mu must be held.
+func (s *Weighted) notifyEDF() {
+	for {
+		var next *waiter
+		for e := s.waiters.Front(); e != nil; e = e.Next() {
+			w := e.Value.(*waiter)
+			if w.n > s.size-s.cur {
+				continue
+			}
+			if next == nil || w.earlier(next) {
+				next = w
+			}
+		}
+		if next == nil {
+			return // No waiter fits.
+		}
+		s.serve(next)
+	}
+}
+
+// earlier reports whether w has an earlier deadline than v, a deadline
+// being earlier than none.
+func (w *waiter) earlier(v *waiter) bool {
+	switch {
+	case w.deadline.IsZero():
+		return false
+	case v.deadline.IsZero():
+		return true
+	}
+	return w.deadline.Before(v.deadline)
+}
@@ -0,0 +1,63 @@
+// Copyright 2026 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package semaphore_test
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"golang.org/x/sync/semaphore"
+)
+
+func TestWeightedSchedulerEDF(t *testing.T) {
+	t.Parallel()
+
+	sem := semaphore.NewWeighted(2)
+	sem.SetScheduler(semaphore.EDF)
+	sem.Acquire(context.Background(), 2)
+
+	waiters := []struct {
+		name     string
+		n        int64
+		deadline time.Duration // 0 for none
+	}{
+		{"none", 1, 0},
+		{"late", 1, time.Hour},
+		{"soon", 1, 10 * time.Minute},
+		{"sooner but large", 2, 5 * time.Minute},
+	}
+	acquired := make(chan string)
+	for i, w := range waiters {
+		ctx := context.Background()
+		if w.deadline > 0 {
+			var cancel context.CancelFunc
+			ctx, cancel = context.WithTimeout(ctx, w.deadline)
+			defer cancel()
+		}
+		go func() {
+			if err := sem.Acquire(ctx, w.n); err != nil {
+				t.Error(err)
+			}
+			acquired <- w.name
+		}()
+		waitForQueueLen(sem, i+1)
+	}
+
+	for _, step := range []struct {
+		release int64
+		want    string
+	}{
+		{1, "soon"},             // "sooner but large" does not fit.
+		{1, "late"},             // Nor does it now.
+		{2, "sooner but large"}, // Now it does.
+		{2, "none"},
+	} {
+		sem.Release(step.release)
+		if got := <-acquired; got != step.want {
+			t.Fatalf("after releasing %d, %q acquired; want %q", step.release, got, step.want)
+		}
+	}
+}
@@ -8,16 +8,40 @@ package semaphore // import "golang.org/x/sync/semaphore"
 import (
 	"container/list"
 	"context"
+	"errors"
 	"sync"
+	"sync/atomic"
+	"time"
 )
 
+// ErrWeightTooLarge is returned by Acquire when the requested weight exceeds
+// the size of the semaphore and the Context can never be canceled, so that
+// waiting would block forever, or when the size of the semaphore is 0.
+var ErrWeightTooLarge = errors.New("semaphore: weight exceeds semaphore size")
+
+// ErrQueueFull is returned by Acquire when the caller would have to wait but
+// the maximum number of waiters set with SetMaxWaiters are already waiting.
+var ErrQueueFull = errors.New("semaphore: too many waiters")
+
 type waiter struct {
-	n     int64
-	ready chan<- struct{} // Closed when semaphore acquired.
+	n        int64
+	priority int             // Base priority; higher is served first.
+	since    time.Time       // When the waiter was queued, for aging.
+	ready    chan<- struct{} // Closed when semaphore acquired.
+	deadline time.Time       // Of the caller's Context, if any; see EDF.
+
+	elem   *list.Element // Position in waiters or, if parked, in parked.
+	parked bool
 }
 
 // NewWeighted creates a new weighted semaphore with the given
 // maximum combined weight for concurrent access.
+//
+// A semaphore of size 0 acts as a closed gate: Acquire and TryAcquire of a
+// weight of 0 succeed, while for any larger weight TryAcquire fails and
+// Acquire returns ErrWeightTooLarge at once, without waiting, until the gate
+// is opened with Resize. Only callers that were already waiting when a
+// semaphore was shrunk to size 0 keep waiting for it to be opened again.
 func NewWeighted(n int64) *Weighted {
 	w := &Weighted{size: n}
 	return w
@@ -30,6 +54,43 @@ type Weighted struct {
 	cur     int64
 	mu      sync.Mutex
 	waiters list.List
+	parked  list.List // Waiters too large for the current size; see Resize.
+
+	prioritized int           // Number of waiters with a non-zero priority.
+	aging       time.Duration // See SetAging.
+
+	// blocked is the weight needed by the waiter that notifyWaiters last
+	// found unable to proceed, or 0 if it must be recomputed. Release skips
+	// notifyWaiters until at least that much is available, so that a burst
+	// of small releases makes a single pass over the waiters.
+	blocked int64
+
+	usage     float64   // Token-seconds held up to usageTime; see TokenSeconds.
+	usageTime time.Time // When cur last changed.
+	peak      int64     // See PeakUsage.
+
+	tags map[string]int64 // Weight held per tag; see AcquireTagged.
+
+	identities  map[string]int64         // Weight held or awaited per identity; see AcquireAs.
+	identityCap int64                    // See SetPerIdentityCap.
+	classes     map[string]*classReserve // See ReserveClass.
+
+	maxWaiters int // See SetMaxWaiters.
+
+	orderHook func(seq int64) // See SetAcquireOrderHook.
+	seq       int64
+
+	fast, slow atomic.Int64 // See PathStats.
+
+	debug bool      // See SetDebug.
+	sched Scheduler // See SetScheduler.
+
+	warnAfter time.Duration                       // See SetSlowWaitWarning.
+	warn      func(n int64, waited time.Duration) // See SetSlowWaitWarning.
+
+	released chan struct{} // If non-nil, closed by the next Release.
+
+	id atomic.Uint64 // Lock order for TransferCapacity; see lockID.
 }
 
 // Acquire acquires the semaphore with a weight of n, blocking until resources
@@ -37,83 +98,581 @@ type Weighted struct {
 // ctx.Err() and leaves the semaphore unchanged.
 //
 // If ctx is already done, Acquire may still succeed without blocking.
+//
+// If n exceeds the size of the semaphore, Acquire blocks until the semaphore
+// is grown by Resize or ctx is done. If ctx can never be done (its Done
+// method returns nil, as for context.Background), Acquire instead returns
+// ErrWeightTooLarge immediately rather than leaking the caller. While the
+// size of the semaphore is 0, Acquire returns ErrWeightTooLarge immediately
+// for any n > 0, whatever ctx; see NewWeighted.
 func (s *Weighted) Acquire(ctx context.Context, n int64) error {
+	return s.AcquirePriority(ctx, n, 0)
+}
+
+// AcquirePriority is like Acquire, but if the caller has to wait it is queued
+// with the given priority. Waiters with a higher priority are served before
+// waiters with a lower one; waiters with equal priority are served in the
+// order in which they arrived. Acquire uses priority 0.
+//
+// Pure priority ordering can starve low-priority waiters indefinitely; see
+// SetAging.
+func (s *Weighted) AcquirePriority(ctx context.Context, n int64, priority int) error {
+	_, err := s.acquire(ctx, n, priority, nil)
+	return err
+}
+
+// AcquireOrSignal is like Acquire, but also stops waiting when ext is closed
+// or receives a value, in which case it returns viaSignal true without having
+// acquired the semaphore. This lets a caller's own admission logic grant a
+// permit in place of the semaphore's tokens.
+//
+// If the semaphore is acquired, viaSignal is false and the caller must
+// Release the weight as usual.
+func (s *Weighted) AcquireOrSignal(ctx context.Context, n int64, ext <-chan struct{}) (viaSignal bool, err error) {
+	return s.acquire(ctx, n, 0, ext)
+}
+
+// AcquireOrWait acquires the semaphore with a weight of n as TryAcquire
+// would, without waiting, if that is possible; otherwise it waits as Acquire
+// does until resources are available or ctx is done. Both steps are taken
+// under a single acquisition of the semaphore's lock, so AcquireOrWait is
+// cheaper than calling TryAcquire and then Acquire, and unlike that pair it
+// cannot lose its place to callers arriving in between.
+//
+// AcquireOrWait is equivalent to Acquire, which already takes the
+// non-blocking path when it can; it exists to make that intent explicit.
+func (s *Weighted) AcquireOrWait(ctx context.Context, n int64) error {
+	return s.Acquire(ctx, n)
+}
+
+// testHookSlowPath, if non-nil, is called when acquire has to queue.
+var testHookSlowPath func()
+
+func (s *Weighted) acquire(ctx context.Context, n int64, priority int, ext <-chan struct{}) (viaSignal bool, err error) {
 	s.mu.Lock()
 	if s.size-s.cur >= n && s.waiters.Len() == 0 {
+		s.account()
 		s.cur += n
+		s.acquired()
 		s.mu.Unlock()
-		return nil
+		s.fast.Add(1)
+		return false, nil
 	}
 
-	if n > s.size {
-		// Don't make other Acquire calls block on one that's doomed to fail.
+	if n > s.size && (s.size == 0 || ctx.Done() == nil && ext == nil) {
+		s.mu.Unlock()
+		return false, ErrWeightTooLarge
+	}
+
+	if s.maxWaiters > 0 && s.waiters.Len()+s.parked.Len() >= s.maxWaiters {
 		s.mu.Unlock()
-		<-ctx.Done()
-		return ctx.Err()
+		return false, ErrQueueFull
 	}
 
+	s.slow.Add(1)
+	if testHookSlowPath != nil {
+		testHookSlowPath()
+	}
 	ready := make(chan struct{})
-	w := waiter{n: n, ready: ready}
-	elem := s.waiters.PushBack(w)
+	w := &waiter{n: n, priority: priority, since: time.Now(), ready: ready}
+	w.deadline, _ = ctx.Deadline()
+	if n > s.size {
+		// Don't make other Acquire calls block on one that's doomed to fail
+		// unless the semaphore grows: park it outside the queue until Resize
+		// makes room for it.
+		s.park(w)
+	} else {
+		s.push(w)
+		if (s.prioritized > 0 || s.sched == EDF) && s.size > s.cur {
+			// w may have overtaken the waiters ahead of it, and it may fit
+			// where they did not.
+			s.notifyWaiters()
+		}
+	}
+	warnAfter, warn := s.warnAfter, s.warn
 	s.mu.Unlock()
 
+	if warn != nil {
+		t := time.AfterFunc(warnAfter, func() {
+			select {
+			case <-ready:
+			default:
+				warn(n, time.Since(w.since))
+			}
+		})
+		defer t.Stop()
+	}
+
 	select {
 	case <-ctx.Done():
-		err := ctx.Err()
-		s.mu.Lock()
-		select {
-		case <-ready:
-			// Acquired the semaphore after we were canceled.  Rather than trying to
-			// fix up the queue, just pretend we didn't notice the cancelation.
-			err = nil
-		default:
-			isFront := s.waiters.Front() == elem
-			s.waiters.Remove(elem)
-			// If we're at the front and there're extra tokens left, notify other waiters.
-			if isFront && s.size > s.cur {
-				s.notifyWaiters()
-			}
+		if s.abandon(w, ready) {
+			return false, nil
 		}
-		s.mu.Unlock()
-		return err
+		return false, ctx.Err()
+
+	case <-ext:
+		if s.abandon(w, ready) {
+			return false, nil
+		}
+		return true, nil
 
 	case <-ready:
-		return nil
+		return false, nil
+	}
+}
+
+// abandon removes w, whose caller has stopped waiting, from the queue. It
+// reports whether w acquired the semaphore before it could be removed.
+func (s *Weighted) abandon(w *waiter, ready <-chan struct{}) (acquired bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	select {
+	case <-ready:
+		// Acquired the semaphore after we stopped waiting.  Rather than trying to
+		// fix up the queue, just pretend we didn't notice.
+		return true
+	default:
+		s.remove(w)
+		// If there're extra tokens left, notify other waiters. This matters
+		// most if we were at the front, but serving the queue is cheap and
+		// this way a satisfiable front waiter can never be stranded.
+		if s.size > s.cur {
+			s.notifyWaiters()
+		}
+		return false
 	}
 }
 
+// MustAcquire is like Acquire but panics if Acquire returns an error.
+//
+// It is intended for tests and short programs in which cancelation is not
+// expected; code serving requests should call Acquire and handle its error.
+func (s *Weighted) MustAcquire(ctx context.Context, n int64) {
+	if err := s.Acquire(ctx, n); err != nil {
+		panic("semaphore: MustAcquire: " + err.Error())
+	}
+}
+
+// AcquireClassified is like Acquire, but also reports whether a failure was
+// caused by ctx reaching its deadline, as opposed to ctx being canceled or
+// any other error, so callers deciding whether to retry need not inspect
+// the error themselves. On success, returns false and nil.
+func (s *Weighted) AcquireClassified(ctx context.Context, n int64) (timedOut bool, err error) {
+	err = s.Acquire(ctx, n)
+	return errors.Is(err, context.DeadlineExceeded), err
+}
+
+// AcquireWithFallback acquires a weight of n from primary if it can do so
+// without blocking, and otherwise from secondary, blocking as Acquire does.
+// It returns the semaphore that was acquired, which the caller must later
+// Release. On failure, it returns a nil semaphore and the error from
+// secondary.Acquire.
+func AcquireWithFallback(ctx context.Context, primary, secondary *Weighted, n int64) (used *Weighted, err error) {
+	if primary.TryAcquire(n) {
+		return primary, nil
+	}
+	if err := secondary.Acquire(ctx, n); err != nil {
+		return nil, err
+	}
+	return secondary, nil
+}
+
 // TryAcquire acquires the semaphore with a weight of n without blocking.
 // On success, returns true. On failure, returns false and leaves the semaphore unchanged.
 func (s *Weighted) TryAcquire(n int64) bool {
 	s.mu.Lock()
 	success := s.size-s.cur >= n && s.waiters.Len() == 0
 	if success {
+		s.account()
 		s.cur += n
+		s.acquired()
 	}
 	s.mu.Unlock()
 	return success
 }
 
+// TryAcquireAll acquires the whole semaphore without blocking, reading its
+// size under the same lock as the acquisition so that a concurrent Resize
+// cannot leave part of it unacquired. On success, returns true, and the
+// caller holds a weight equal to the size of the semaphore at the time of
+// the call. On failure, returns false and leaves the semaphore unchanged.
+func (s *Weighted) TryAcquireAll() bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.cur != 0 || s.waiters.Len() != 0 {
+		return false
+	}
+	s.account()
+	s.cur = s.size
+	s.acquired()
+	return true
+}
+
+// PathStats returns the number of calls to Acquire and its variants that
+// acquired the semaphore at once, without waiting, and the number that had to
+// wait, whatever the outcome of their wait. Comparing the two measures how
+// contended the semaphore is.
+func (s *Weighted) PathStats() (fast, slow int64) {
+	return s.fast.Load(), s.slow.Load()
+}
+
+// CanAcquire reports whether TryAcquire(n) would succeed at this moment,
+// without acquiring anything. The answer may be stale by the time the caller
+// acts on it, as other callers may acquire or release the semaphore
+// concurrently.
+func (s *Weighted) CanAcquire(n int64) bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.size-s.cur >= n && s.waiters.Len() == 0
+}
+
+// A Reservation is a set of tokens held aside by Reserve until it is
+// committed or canceled.
+type Reservation struct {
+	s    *Weighted
+	n    int64
+	done bool // Committed or canceled; guarded by s.mu.
+}
+
+// Reserve sets aside n tokens without blocking, for work that will be
+// admitted later. On success, returns a Reservation and true. On failure,
+// returns nil and false and leaves the semaphore unchanged.
+//
+// Reserved tokens are unavailable to other callers exactly as if they had
+// been acquired. The caller must eventually Commit or Cancel the reservation;
+// an abandoned reservation leaks its tokens.
+func (s *Weighted) Reserve(n int64) (*Reservation, bool) {
+	if !s.TryAcquire(n) {
+		return nil, false
+	}
+	return &Reservation{s: s, n: n}, true
+}
+
+// Commit finalizes the reservation, turning it into an ordinary hold of its
+// tokens that must later be returned with Release. It reports whether the
+// reservation was still outstanding; committing or canceling a reservation
+// that has already been committed or canceled has no effect.
+func (r *Reservation) Commit() bool {
+	return r.finish()
+}
+
+// Cancel returns the reserved tokens to the semaphore. It reports whether
+// the reservation was still outstanding; committing or canceling a
+// reservation that has already been committed or canceled has no effect.
+func (r *Reservation) Cancel() bool {
+	if !r.finish() {
+		return false
+	}
+	r.s.Release(r.n)
+	return true
+}
+
+func (r *Reservation) finish() bool {
+	r.s.mu.Lock()
+	defer r.s.mu.Unlock()
+	if r.done {
+		return false
+	}
+	r.done = true
+	return true
+}
+
 // Release releases the semaphore with a weight of n.
 func (s *Weighted) Release(n int64) {
 	s.mu.Lock()
+	s.account()
 	s.cur -= n
 	if s.cur < 0 {
 		s.mu.Unlock()
 		panic("semaphore: released more than held")
 	}
+	if s.size-s.cur >= s.blocked {
+		s.notifyWaiters()
+	}
+	if s.released != nil {
+		close(s.released)
+		s.released = nil
+	}
+	s.mu.Unlock()
+}
+
+// TokenSeconds returns the total usage of the semaphore so far: the sum,
+// over every acquisition, of its weight multiplied by the number of seconds
+// for which it was held, including acquisitions still held. Reserved tokens
+// count as held.
+func (s *Weighted) TokenSeconds() float64 {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.account()
+	return s.usage
+}
+
+// PeakUsage returns the largest combined weight held at once since s was
+// created or since the last call to ResetPeak.
+func (s *Weighted) PeakUsage() int64 {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.peak
+}
+
+// ResetPeak starts a new observation period for PeakUsage, from the weight
+// currently held.
+func (s *Weighted) ResetPeak() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.peak = s.cur
+}
+
+// account adds the usage since cur last changed to s.usage. It must be
+// called before every change to cur.
+// s.mu must be held.
+func (s *Weighted) account() {
+	now := time.Now()
+	if s.cur > 0 {
+		s.usage += float64(s.cur) * now.Sub(s.usageTime).Seconds()
+	}
+	s.usageTime = now
+}
+
+// SetMaxWaiters limits the number of callers waiting to acquire s to n, so
+// that under overload callers fail fast rather than queue: once n callers are
+// waiting, Acquire and its variants return ErrQueueFull instead of waiting.
+// Callers that can acquire the semaphore at once are not affected. An n <= 0
+// removes the limit, which is the default.
+func (s *Weighted) SetMaxWaiters(n int) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.maxWaiters = n
+}
+
+// SetAcquireOrderHook makes s call hook each time it is acquired, with a
+// sequence number that increases by one with each acquisition, so that
+// tests can check the order in which callers were served. hook is called
+// with the semaphore's lock held, at the moment of acquisition and before
+// the caller returns; it must not call methods of s. A nil hook removes it.
+func (s *Weighted) SetAcquireOrderHook(hook func(seq int64)) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.orderHook = hook
+}
+
+// acquired records an acquisition, which has just increased cur, for
+// PeakUsage and reports it to the order hook, if any.
+// s.mu must be held.
+func (s *Weighted) acquired() {
+	s.peak = max(s.peak, s.cur)
+	if s.orderHook != nil {
+		s.seq++
+		s.orderHook(s.seq)
+	}
+}
+
+// SetSlowWaitWarning makes s call warn, on a goroutine of its own, for each
+// caller that has waited threshold to acquire s without having acquired it
+// or given up, with the weight it asked for and the time it has waited so
+// far. warn is called at most once per call to Acquire or its variants, and
+// not at all for callers that do not have to wait. Only callers that start
+// waiting after SetSlowWaitWarning is called are affected. A nil warn
+// disables the warning, which is the default.
+func (s *Weighted) SetSlowWaitWarning(threshold time.Duration, warn func(n int64, waited time.Duration)) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.warnAfter = threshold
+	s.warn = warn
+}
+
+// SetAging makes queued waiters gain one level of priority for every d they
+// spend waiting, so that a low-priority waiter eventually overtakes newer
+// higher-priority arrivals instead of starving. A d <= 0 disables aging,
+// which is the default.
+func (s *Weighted) SetAging(d time.Duration) {
+	s.mu.Lock()
+	s.aging = d
+	s.blocked = 0
+	// The effective priorities may have changed, so the next waiter may now
+	// fit where the previous one did not.
 	s.notifyWaiters()
 	s.mu.Unlock()
 }
 
+// Resize changes the maximum combined weight of the semaphore to n.
+//
+// Growing the semaphore wakes any waiters that can now be satisfied,
+// including those that asked for more than the previous size. Shrinking it
+// does not affect current holders, but new acquisitions cannot succeed until
+// enough weight has been released to fit under the new size.
+//
+// Waiters that asked for more than the new size are set aside: they keep
+// waiting, without holding up the waiters queued behind them, until the
+// semaphore is grown enough for them or their Context is done. This matches
+// what a call to Acquire made after the Resize would do, except when the new
+// size is 0: such a call fails at once, while the waiters queued before the
+// shrink survive it.
+func (s *Weighted) Resize(n int64) {
+	s.mu.Lock()
+	s.resize(n)
+	s.mu.Unlock()
+}
+
+// resize implements Resize.
+// s.mu must be held.
+func (s *Weighted) resize(n int64) {
+	s.size = n
+	s.blocked = 0
+	// Park queued waiters that no longer fit, so that an impossible request
+	// at the front cannot starve the rest of the queue.
+	for e := s.waiters.Front(); e != nil; {
+		w := e.Value.(*waiter)
+		e = e.Next()
+		if w.n > s.size {
+			s.remove(w)
+			s.park(w)
+		}
+	}
+	// Queue parked waiters that now fit, in the order in which they arrived.
+	// This happens under the same lock as their parking, so a waiter cannot
+	// miss a Resize that races with its Acquire.
+	for e := s.parked.Front(); e != nil; {
+		w := e.Value.(*waiter)
+		e = e.Next()
+		if w.n <= s.size {
+			s.remove(w)
+			s.push(w)
+		}
+	}
+	s.notifyWaiters()
+}
+
+// Shrink is like Resize, but for reducing the size of the semaphore: after
+// setting the size to n, it blocks until the weight held by callers fits
+// within n, so that the caller knows the reduction has fully taken effect.
+// On success, returns nil. If ctx is done first, Shrink returns ctx.Err(),
+// and the semaphore keeps its new size.
+func (s *Weighted) Shrink(ctx context.Context, n int64) error {
+	s.Resize(n)
+	return s.waitHeld(ctx, n)
+}
+
+// WaitIdle blocks until no weight is held, that is, until every acquisition
+// has been released, without acquiring anything itself: by the time it
+// returns, other callers may have acquired the semaphore again. It serves as
+// a barrier, for instance in tests that must wait for all workers to finish
+// before checking their results. On success, returns nil. If ctx is done
+// first, WaitIdle returns ctx.Err().
+func (s *Weighted) WaitIdle(ctx context.Context) error {
+	return s.waitHeld(ctx, 0)
+}
+
+// waitHeld blocks until the weight held is at most n or ctx is done.
+func (s *Weighted) waitHeld(ctx context.Context, n int64) error {
+	for {
+		s.mu.Lock()
+		if s.cur <= n {
+			s.mu.Unlock()
+			return nil
+		}
+		if s.released == nil {
+			s.released = make(chan struct{})
+		}
+		released := s.released
+		s.mu.Unlock()
+
+		select {
+		case <-released:
+		case <-ctx.Done():
+			return ctx.Err()
+		}
+	}
+}
+
+// snapshot returns the size of s, the weight currently held, and the number
+// of waiters, all read at the same instant.
+func (s *Weighted) snapshot() (size, cur int64, waiters int) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.size, s.cur, s.waiters.Len() + s.parked.Len()
+}
+
+func (s *Weighted) push(w *waiter) {
+	if w.priority != 0 {
+		s.prioritized++
+	}
+	if s.prioritized > 0 || s.sched == EDF {
+		// w may now be the next waiter to serve, even with a priority of 0,
+		// as it may overtake waiters with a negative priority.
+		s.blocked = 0
+	}
+	w.elem = s.waiters.PushBack(w)
+	w.parked = false
+}
+
+// park sets w aside in the parked list, which is kept in the order in which
+// the waiters arrived.
+func (s *Weighted) park(w *waiter) {
+	e := s.parked.Back()
+	for e != nil && e.Value.(*waiter).since.After(w.since) {
+		e = e.Prev()
+	}
+	if e == nil {
+		w.elem = s.parked.PushFront(w)
+	} else {
+		w.elem = s.parked.InsertAfter(w, e)
+	}
+	w.parked = true
+}
+
+func (s *Weighted) remove(w *waiter) {
+	if w.parked {
+		s.parked.Remove(w.elem)
+		return
+	}
+	if w.priority != 0 {
+		s.prioritized--
+	}
+	s.waiters.Remove(w.elem)
+	s.blocked = 0
+}
+
+// front returns the waiter to be served next: the one with the highest
+// effective priority, with ties going to the one that has waited longest.
+func (s *Weighted) front() *waiter {
+	e := s.waiters.Front()
+	if e == nil {
+		return nil
+	}
+	front := e.Value.(*waiter)
+	if s.prioritized == 0 {
+		// All waiters have the same base priority and age at the same rate,
+		// so the queue is already in order.
+		return front
+	}
+
+	now := time.Now()
+	best := 0
+	for ; e != nil; e = e.Next() {
+		w := e.Value.(*waiter)
+		p := w.priority
+		if s.aging > 0 {
+			p += int(now.Sub(w.since) / s.aging)
+		}
+		if w == front || p > best {
+			front, best = w, p
+		}
+	}
+	return front
+}
+
 func (s *Weighted) notifyWaiters() {
+	if s.sched == EDF {
+		s.notifyEDF()
+		return
+	}
 	for {
-		next := s.waiters.Front()
-		if next == nil {
+		w := s.front()
+		if w == nil {
 			break // No more waiters blocked.
 		}
 
-		w := next.Value.(waiter)
 		if s.size-s.cur < w.n {
 			// Not enough tokens for the next waiter.  We could keep going (to try to
 			// find a waiter with a smaller request), but under load that could cause
@@ -126,11 +685,24 @@ func (s *Weighted) notifyWaiters() {
 			// of the readers.  If we allow the readers to jump ahead in the queue,
 			// the writer will starve — there is always one token available for every
 			// reader.
+			if s.aging <= 0 {
+				// Without aging, the next waiter can only change when the
+				// queue does.
+				s.blocked = w.n
+			}
 			break
 		}
 
-		s.cur += w.n
-		s.waiters.Remove(next)
-		close(w.ready)
+		s.serve(w)
 	}
 }
+
+// serve grants the semaphore to the queued waiter w.
+// s.mu must be held.
+func (s *Weighted) serve(w *waiter) {
+	s.account()
+	s.cur += w.n
+	s.acquired()
+	s.remove(w)
+	close(w.ready)
+}
@@ -10,6 +10,7 @@ package semaphore_test
 import (
 	"context"
 	"fmt"
+	"sync"
 	"testing"
 
 	"golang.org/x/sync/semaphore"
@@ -130,3 +131,39 @@ func BenchmarkAcquireSeq(b *testing.B) {
 		}
 	}
 }
+
+// BenchmarkReleaseBurst measures a burst of small releases while prioritized
+// waiters that cannot yet be satisfied are queued.
+func BenchmarkReleaseBurst(b *testing.B) {
+	const size = 256
+	ctx := context.Background()
+	for _, queued := range []int{1, 64} {
+		b.Run(fmt.Sprintf("queued-%d", queued), func(b *testing.B) {
+			for i := 0; i < b.N; i++ {
+				b.StopTimer()
+				sem := semaphore.NewWeighted(size)
+				sem.Acquire(ctx, size)
+				var wg sync.WaitGroup
+				for j := 0; j < queued; j++ {
+					wg.Add(1)
+					priority := j%2 + 1
+					go func() {
+						defer wg.Done()
+						sem.AcquirePriority(ctx, size, priority)
+						sem.Release(size)
+					}()
+				}
+				waitForQueueLen(sem, queued)
+				b.StartTimer()
+
+				for j := 0; j < size; j++ {
+					sem.Release(1)
+				}
+
+				b.StopTimer()
+				wg.Wait()
+				b.StartTimer()
+			}
+		})
+	}
+}
@@ -9,6 +9,7 @@ import (
 	"math/rand"
 	"runtime"
 	"sync"
+	"sync/atomic"
 	"testing"
 	"time"
 
@@ -200,3 +201,877 @@ func TestAllocCancelDoesntStarve(t *testing.T) {
 	}
 	sem.Release(1)
 }
+
+// waitForQueueLen blocks until n callers are waiting to acquire sem.
+func waitForQueueLen(sem *semaphore.Weighted, n int) {
+	for sem.QueueLen() != n {
+		runtime.Gosched()
+	}
+}
+
+func TestWeightedAcquirePriority(t *testing.T) {
+	t.Parallel()
+
+	ctx := context.Background()
+	cases := []struct {
+		aging time.Duration
+		want  string
+	}{
+		{aging: 0, want: "high"},
+		{aging: time.Millisecond, want: "low"},
+	}
+	for _, tc := range cases {
+		sem := semaphore.NewWeighted(1)
+		sem.SetAging(tc.aging)
+		sem.Acquire(ctx, 1)
+
+		acquired := make(chan string, 2)
+		acquire := func(name string, priority int) {
+			go func() {
+				if err := sem.AcquirePriority(ctx, 1, priority); err != nil {
+					t.Errorf("AcquirePriority(_, 1, %d) = %v", priority, err)
+				}
+				acquired <- name
+			}()
+		}
+
+		// With aging, a low-priority waiter that has been queued for 50ms
+		// outranks a high-priority waiter that has only just arrived.
+		acquire("low", 0)
+		waitForQueueLen(sem, 1)
+		time.Sleep(50 * time.Millisecond)
+		acquire("high", 10)
+		waitForQueueLen(sem, 2)
+
+		sem.Release(1)
+		if got := <-acquired; got != tc.want {
+			t.Errorf("with aging %v, %s waiter acquired first; want %s", tc.aging, got, tc.want)
+		}
+		sem.Release(1)
+		<-acquired
+		sem.Release(1)
+	}
+}
+
+func TestWeightedReserve(t *testing.T) {
+	t.Parallel()
+
+	sem := semaphore.NewWeighted(3)
+
+	r, ok := sem.Reserve(2)
+	if !ok {
+		t.Fatal("Reserve(2) on an empty semaphore failed")
+	}
+	if _, ok := sem.Reserve(2); ok {
+		t.Error("Reserve(2) succeeded with only 1 token available")
+	}
+	if sem.TryAcquire(2) {
+		t.Error("TryAcquire(2) succeeded with 2 of 3 tokens reserved")
+	}
+
+	// Canceling returns the reserved tokens.
+	if !r.Cancel() {
+		t.Error("Cancel of an outstanding reservation reported false")
+	}
+	if r.Cancel() || r.Commit() {
+		t.Error("second Cancel or Commit of a reservation reported true")
+	}
+	if !sem.TryAcquire(3) {
+		t.Fatal("TryAcquire(3) failed after canceling the reservation")
+	}
+	sem.Release(3)
+
+	// Committing keeps the tokens held until they are released.
+	r, ok = sem.Reserve(2)
+	if !ok {
+		t.Fatal("Reserve(2) on an empty semaphore failed")
+	}
+	if !r.Commit() {
+		t.Error("Commit of an outstanding reservation reported false")
+	}
+	if r.Cancel() {
+		t.Error("Cancel of a committed reservation reported true")
+	}
+	if sem.TryAcquire(2) {
+		t.Error("TryAcquire(2) succeeded with 2 of 3 tokens committed")
+	}
+	sem.Release(2)
+	if !sem.TryAcquire(3) {
+		t.Error("TryAcquire(3) failed after releasing the committed tokens")
+	}
+}
+
+func TestWeightedResize(t *testing.T) {
+	t.Parallel()
+
+	ctx := context.Background()
+	sem := semaphore.NewWeighted(1)
+	sem.Acquire(ctx, 1)
+
+	sem.Resize(3)
+	if !sem.TryAcquire(2) {
+		t.Fatal("TryAcquire(2) failed after growing a semaphore of size 1 to 3")
+	}
+
+	sem.Resize(1)
+	sem.Release(2)
+	if sem.TryAcquire(1) {
+		t.Error("TryAcquire(1) succeeded with 1 held on a semaphore shrunk to 1")
+	}
+	sem.Release(1)
+	if !sem.TryAcquire(1) {
+		t.Error("TryAcquire(1) failed on an empty semaphore of size 1")
+	}
+}
+
+// TestWeightedShrinkWithOversizedWaiter checks that a waiter left too large
+// for a shrunk semaphore is parked rather than blocking smaller waiters, and
+// is served once the semaphore grows again.
+func TestWeightedShrinkWithOversizedWaiter(t *testing.T) {
+	t.Parallel()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	sem := semaphore.NewWeighted(10)
+	sem.Acquire(ctx, 5)
+
+	large := make(chan error, 1)
+	go func() { large <- sem.Acquire(ctx, 8) }()
+	waitForQueueLen(sem, 1)
+	small := make(chan error, 1)
+	go func() { small <- sem.Acquire(ctx, 1) }()
+	waitForQueueLen(sem, 2)
+
+	// The large waiter can no longer be satisfied; it must not block the
+	// small one.
+	sem.Resize(5)
+	if n := sem.QueueLen(); n != 1 {
+		t.Fatalf("QueueLen() after shrinking = %d; want 1", n)
+	}
+	sem.Release(1)
+	select {
+	case err := <-small:
+		if err != nil {
+			t.Fatalf("small Acquire = %v", err)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("small waiter starved behind an oversized one")
+	}
+
+	// Growing the semaphore again serves the large waiter.
+	sem.Release(5)
+	sem.Resize(10)
+	if err := <-large; err != nil {
+		t.Fatalf("large Acquire after growing = %v", err)
+	}
+}
+
+// TestWeightedResizeDuringAcquire checks that growing a semaphore wakes an
+// Acquire that is too large for the old size, however the two race.
+func TestWeightedResizeDuringAcquire(t *testing.T) {
+	t.Parallel()
+
+	for i := 0; i < 1000; i++ {
+		sem := semaphore.NewWeighted(1)
+		ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+
+		done := make(chan error, 1)
+		go func() {
+			done <- sem.Acquire(ctx, 2)
+		}()
+		if i%2 == 0 {
+			runtime.Gosched()
+		}
+		sem.Resize(2)
+
+		if err := <-done; err != nil {
+			t.Fatalf("Acquire(_, 2) = %v after Resize(2); want nil", err)
+		}
+		cancel()
+	}
+}
+
+func TestWeightedTooLargeWithBackground(t *testing.T) {
+	t.Parallel()
+
+	sem := semaphore.NewWeighted(2)
+	done := make(chan error, 1)
+	go func() {
+		done <- sem.Acquire(context.Background(), 3)
+	}()
+
+	select {
+	case err := <-done:
+		if err != semaphore.ErrWeightTooLarge {
+			t.Errorf("Acquire(context.Background(), 3) = %v; want %v", err, semaphore.ErrWeightTooLarge)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("Acquire(context.Background(), 3) on a semaphore of size 2 blocked")
+	}
+}
+
+// TestWeightedReleaseBurst checks that no waiter is missed when many small
+// releases make room for the queued waiters in several steps.
+func TestWeightedReleaseBurst(t *testing.T) {
+	t.Parallel()
+
+	const size = 64
+	ctx := context.Background()
+	sem := semaphore.NewWeighted(size)
+	sem.Acquire(ctx, size)
+
+	weights := []int64{size, 1, 3, size / 2, 7, 1, size, 5}
+	var wg sync.WaitGroup
+	acquired := make(chan int64, len(weights))
+	for i, n := range weights {
+		i, n := i, n
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			if err := sem.AcquirePriority(ctx, n, i%3); err != nil {
+				t.Errorf("AcquirePriority(_, %d, %d) = %v", n, i%3, err)
+				return
+			}
+			acquired <- n
+		}()
+		waitForQueueLen(sem, i+1)
+	}
+
+	for i := 0; i < size; i++ {
+		sem.Release(1)
+	}
+	for range weights {
+		select {
+		case n := <-acquired:
+			sem.Release(n)
+		case <-time.After(5 * time.Second):
+			t.Fatalf("waiters stalled with %d still queued", sem.QueueLen())
+		}
+	}
+	wg.Wait()
+	if !sem.TryAcquire(size) {
+		t.Errorf("TryAcquire(%d) failed once all waiters released", size)
+	}
+}
+
+// TestWeightedPriorityOvertakesBlocked checks that a priority-0 waiter that
+// overtakes a blocked waiter with a negative priority is served as soon as
+// it fits.
+func TestWeightedPriorityOvertakesBlocked(t *testing.T) {
+	t.Parallel()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	sem := semaphore.NewWeighted(10)
+	sem.MustAcquire(ctx, 10)
+
+	large := make(chan error, 1)
+	go func() { large <- sem.AcquirePriority(ctx, 10, -1) }()
+	waitForQueueLen(sem, 1)
+	// Serve the queue with nothing to spare, so that the large waiter is
+	// recorded as blocked.
+	sem.Release(0)
+
+	small := make(chan error, 1)
+	go func() { small <- sem.AcquirePriority(ctx, 1, 0) }()
+	waitForQueueLen(sem, 2)
+	sem.Release(1)
+	select {
+	case err := <-small:
+		if err != nil {
+			t.Fatalf("small AcquirePriority = %v", err)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("priority-0 waiter not woken once it fit")
+	}
+	cancel()
+	if err := <-large; err != context.Canceled {
+		t.Errorf("large AcquirePriority = %v; want %v", err, context.Canceled)
+	}
+}
+
+func TestWeightedMustAcquire(t *testing.T) {
+	t.Parallel()
+
+	sem := semaphore.NewWeighted(1)
+	sem.MustAcquire(context.Background(), 1)
+	sem.Release(1)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	sem.Acquire(context.Background(), 1)
+	defer func() {
+		if recover() == nil {
+			t.Error("MustAcquire with a canceled ctx on a full semaphore did not panic")
+		}
+	}()
+	sem.MustAcquire(ctx, 1)
+}
+
+func TestWeightedAcquireOrSignal(t *testing.T) {
+	t.Parallel()
+
+	ctx := context.Background()
+	sem := semaphore.NewWeighted(2)
+
+	// Tokens available: acquired through the semaphore.
+	viaSignal, err := sem.AcquireOrSignal(ctx, 1, nil)
+	if viaSignal || err != nil {
+		t.Fatalf("AcquireOrSignal with tokens available = %t, %v; want false, nil", viaSignal, err)
+	}
+	sem.Release(1)
+
+	// Tokens released while waiting.
+	sem.Acquire(ctx, 2)
+	ext := make(chan struct{})
+	done := make(chan bool)
+	go func() {
+		viaSignal, err := sem.AcquireOrSignal(ctx, 2, ext)
+		if err != nil {
+			t.Errorf("AcquireOrSignal = %v", err)
+		}
+		done <- viaSignal
+	}()
+	waitForQueueLen(sem, 1)
+	sem.Release(2)
+	if <-done {
+		t.Error("AcquireOrSignal woken by Release reported viaSignal")
+	}
+
+	// External signal while waiting: nothing is acquired and the waiter is
+	// removed from the queue.
+	go func() {
+		viaSignal, err := sem.AcquireOrSignal(ctx, 1, ext)
+		if err != nil {
+			t.Errorf("AcquireOrSignal = %v", err)
+		}
+		done <- viaSignal
+	}()
+	waitForQueueLen(sem, 1)
+	close(ext)
+	if !<-done {
+		t.Error("AcquireOrSignal woken by ext did not report viaSignal")
+	}
+	if n := sem.QueueLen(); n != 0 {
+		t.Errorf("%d waiters queued after the signal; want 0", n)
+	}
+	sem.Release(2)
+	if !sem.TryAcquire(2) {
+		t.Error("TryAcquire(2) failed; the signaled waiter consumed tokens")
+	}
+}
+
+func TestWeightedAcquireOrWait(t *testing.T) {
+	// Not parallel: the slow path hook is global.
+	var slow int32
+	defer semaphore.SetSlowPathHook(func() { atomic.AddInt32(&slow, 1) })()
+
+	ctx := context.Background()
+	sem := semaphore.NewWeighted(2)
+
+	// Uncontended: acquired without queueing.
+	if err := sem.AcquireOrWait(ctx, 2); err != nil {
+		t.Fatalf("AcquireOrWait = %v", err)
+	}
+	if n := atomic.LoadInt32(&slow); n != 0 {
+		t.Fatalf("uncontended AcquireOrWait queued %d times; want 0", n)
+	}
+
+	// Contended: waits like Acquire until the weight is released.
+	done := make(chan error)
+	go func() {
+		done <- sem.AcquireOrWait(ctx, 1)
+	}()
+	waitForQueueLen(sem, 1)
+	if n := atomic.LoadInt32(&slow); n != 1 {
+		t.Fatalf("contended AcquireOrWait queued %d times; want 1", n)
+	}
+	sem.Release(2)
+	if err := <-done; err != nil {
+		t.Fatalf("AcquireOrWait = %v", err)
+	}
+
+	// Canceled: fails like Acquire and leaves the semaphore unchanged.
+	sem.Acquire(ctx, 1)
+	cctx, cancel := context.WithCancel(ctx)
+	cancel()
+	if err := sem.AcquireOrWait(cctx, 1); err != context.Canceled {
+		t.Fatalf("AcquireOrWait with a canceled ctx = %v; want %v", err, context.Canceled)
+	}
+	if !sem.TryAcquire(0) || sem.TryAcquire(1) {
+		t.Fatal("canceled AcquireOrWait changed the semaphore")
+	}
+}
+
+func TestWeightedCanAcquire(t *testing.T) {
+	t.Parallel()
+
+	ctx := context.Background()
+	sem := semaphore.NewWeighted(2)
+	for _, n := range []int64{3, 2, 1, 1, 1} {
+		can := sem.CanAcquire(n)
+		if got := sem.TryAcquire(n); got != can {
+			t.Fatalf("CanAcquire(%d) = %t, but TryAcquire(%d) = %t", n, can, n, got)
+		}
+	}
+
+	// A waiter blocks later callers even if they would fit.
+	sem.Release(1)
+	done := make(chan struct{})
+	go func() {
+		sem.Acquire(ctx, 2)
+		close(done)
+	}()
+	waitForQueueLen(sem, 1)
+	if sem.CanAcquire(1) {
+		t.Error("CanAcquire(1) = true with a waiter queued; want false")
+	}
+	sem.Release(1)
+	<-done
+}
+
+func TestWeightedTokenSeconds(t *testing.T) {
+	t.Parallel()
+
+	ctx := context.Background()
+	sem := semaphore.NewWeighted(10)
+	if got := sem.TokenSeconds(); got != 0 {
+		t.Fatalf("TokenSeconds() of an unused semaphore = %v; want 0", got)
+	}
+
+	const hold = 100 * time.Millisecond
+	start := time.Now()
+	sem.Acquire(ctx, 4)
+	time.Sleep(hold)
+	sem.Release(4)
+	elapsed := time.Since(start)
+
+	got := sem.TokenSeconds()
+	if min, max := 4*hold.Seconds(), 4*elapsed.Seconds(); got < min || got > max {
+		t.Errorf("TokenSeconds() after holding 4 for %v = %v; want in [%v, %v]", hold, got, min, max)
+	}
+	time.Sleep(10 * time.Millisecond)
+	if again := sem.TokenSeconds(); again != got {
+		t.Errorf("TokenSeconds() grew from %v to %v while nothing was held", got, again)
+	}
+}
+
+func TestWeightedPeakUsage(t *testing.T) {
+	t.Parallel()
+
+	ctx := context.Background()
+	sem := semaphore.NewWeighted(10)
+	if p := sem.PeakUsage(); p != 0 {
+		t.Errorf("PeakUsage() of a new semaphore = %d; want 0", p)
+	}
+	sem.Acquire(ctx, 3)
+	sem.TryAcquire(4)
+	sem.Release(3)
+	sem.Acquire(ctx, 2)
+	if p := sem.PeakUsage(); p != 7 {
+		t.Errorf("PeakUsage() = %d; want 7", p)
+	}
+
+	// Weight acquired by a waiter counts too.
+	done := make(chan struct{})
+	go func() {
+		sem.Acquire(ctx, 5)
+		close(done)
+	}()
+	waitForQueueLen(sem, 1)
+	sem.Release(4)
+	<-done
+	if p := sem.PeakUsage(); p != 7 {
+		t.Errorf("PeakUsage() = %d; want 7", p)
+	}
+	sem.Acquire(ctx, 2)
+	if p := sem.PeakUsage(); p != 9 {
+		t.Errorf("PeakUsage() = %d; want 9", p)
+	}
+
+	sem.Release(9)
+	sem.Acquire(ctx, 1)
+	sem.ResetPeak()
+	if p := sem.PeakUsage(); p != 1 {
+		t.Errorf("PeakUsage() after ResetPeak = %d; want 1", p)
+	}
+}
+
+func TestWeightedZeroSizeGate(t *testing.T) {
+	t.Parallel()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	gate := semaphore.NewWeighted(0)
+	if err := gate.Acquire(ctx, 0); err != nil {
+		t.Errorf("Acquire(0) on a closed gate = %v; want nil", err)
+	}
+	if gate.TryAcquire(1) {
+		t.Error("TryAcquire(1) on a closed gate succeeded")
+	}
+	// Acquire fails fast, even with a Context that could be canceled.
+	for _, ctx := range []context.Context{context.Background(), ctx} {
+		if err := gate.Acquire(ctx, 1); err != semaphore.ErrWeightTooLarge {
+			t.Errorf("Acquire(1) on a closed gate = %v; want %v", err, semaphore.ErrWeightTooLarge)
+		}
+	}
+	if n := gate.QueueLen(); n != 0 {
+		t.Errorf("QueueLen() after failed Acquire calls = %d; want 0", n)
+	}
+
+	const n = 3
+	gate.Resize(n)
+	for i := 0; i < n; i++ {
+		if err := gate.Acquire(ctx, 1); err != nil {
+			t.Errorf("Acquire(1) after opening the gate = %v; want nil", err)
+		}
+	}
+	gate.Release(n)
+
+	// A waiter queued before the gate closes again survives the shrink and
+	// is served once the gate reopens, while a new Acquire fails fast.
+	gate.MustAcquire(ctx, n)
+	queued := make(chan error, 1)
+	go func() { queued <- gate.Acquire(ctx, 1) }()
+	waitForQueueLen(gate, 1)
+	gate.Resize(0)
+	if err := gate.Acquire(ctx, 1); err != semaphore.ErrWeightTooLarge {
+		t.Errorf("Acquire(1) on a reclosed gate = %v; want %v", err, semaphore.ErrWeightTooLarge)
+	}
+	gate.Release(n)
+	gate.Resize(1)
+	if err := <-queued; err != nil {
+		t.Errorf("Acquire(1) queued before closing the gate = %v after reopening; want nil", err)
+	}
+}
+
+func TestWeightedCancelMiddleWaiter(t *testing.T) {
+	t.Parallel()
+
+	ctx := context.Background()
+	acquire := func(sem *semaphore.Weighted, ctx context.Context, n int64, priority int) <-chan error {
+		done := make(chan error, 1)
+		go func() { done <- sem.AcquirePriority(ctx, n, priority) }()
+		return done
+	}
+
+	// Without priorities: the front waiter is served once there is room.
+	sem := semaphore.NewWeighted(10)
+	sem.Acquire(ctx, 10)
+	front := acquire(sem, ctx, 5, 0)
+	waitForQueueLen(sem, 1)
+	mctx, cancel := context.WithCancel(ctx)
+	middle := acquire(sem, mctx, 5, 0)
+	waitForQueueLen(sem, 2)
+	back := acquire(sem, ctx, 5, 0)
+	waitForQueueLen(sem, 3)
+	cancel()
+	if err := <-middle; err != context.Canceled {
+		t.Fatalf("canceled middle waiter: Acquire = %v; want %v", err, context.Canceled)
+	}
+	sem.Release(10)
+	for _, done := range []<-chan error{front, back} {
+		if err := <-done; err != nil {
+			t.Fatalf("Acquire = %v", err)
+		}
+	}
+
+	// A waiter that overtakes the queue with a higher priority is served at
+	// once if it fits, even though those it overtook do not.
+	sem = semaphore.NewWeighted(10)
+	sem.Acquire(ctx, 5)
+	mctx, cancel = context.WithCancel(ctx)
+	big := acquire(sem, mctx, 10, 0)
+	waitForQueueLen(sem, 1)
+	select {
+	case err := <-acquire(sem, ctx, 1, 5):
+		if err != nil {
+			t.Fatalf("Acquire = %v", err)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("high-priority waiter that fits not served")
+	}
+	cancel()
+	<-big
+}
+
+func TestWeightedMaxWaiters(t *testing.T) {
+	t.Parallel()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	sem := semaphore.NewWeighted(1)
+	sem.SetMaxWaiters(2)
+	sem.Acquire(ctx, 1)
+
+	done := make(chan error, 2)
+	for i := 0; i < 2; i++ {
+		go func() { done <- sem.Acquire(ctx, 1) }()
+	}
+	waitForQueueLen(sem, 2)
+	if err := sem.Acquire(ctx, 1); err != semaphore.ErrQueueFull {
+		t.Errorf("Acquire with a full queue = %v; want %v", err, semaphore.ErrQueueFull)
+	}
+
+	cancel()
+	for i := 0; i < 2; i++ {
+		<-done
+	}
+	sem.Release(1)
+	if err := sem.Acquire(context.Background(), 1); err != nil {
+		t.Errorf("Acquire with an empty queue = %v; want nil", err)
+	}
+}
+
+func TestWeightedAcquireOrderHook(t *testing.T) {
+	t.Parallel()
+
+	ctx := context.Background()
+	sem := semaphore.NewWeighted(1)
+	var last int64 // Written by the hook, which Release runs on this goroutine.
+	sem.SetAcquireOrderHook(func(seq int64) {
+		if seq != last+1 {
+			t.Errorf("sequence %d follows %d", seq, last)
+		}
+		last = seq
+	})
+	sem.Acquire(ctx, 1)
+
+	const n = 5
+	acquired := make(chan int)
+	for i := 0; i < n; i++ {
+		go func() {
+			sem.Acquire(ctx, 1)
+			acquired <- i
+		}()
+		waitForQueueLen(sem, i+1)
+	}
+	for i := 0; i < n; i++ {
+		sem.Release(1)
+		if got := <-acquired; got != i {
+			t.Errorf("waiter %d acquired the semaphore in position %d", got, i)
+		}
+		if want := int64(i + 2); last != want {
+			t.Errorf("waiter %d acquired with sequence %d; want %d", i, last, want)
+		}
+	}
+	sem.Release(1)
+}
+
+func TestWeightedPathStats(t *testing.T) {
+	t.Parallel()
+
+	ctx := context.Background()
+	sem := semaphore.NewWeighted(2)
+	sem.Acquire(ctx, 1)
+	sem.Acquire(ctx, 1)
+	done := make(chan struct{})
+	go func() {
+		sem.Acquire(ctx, 1)
+		close(done)
+	}()
+	waitForQueueLen(sem, 1)
+	sem.Release(1)
+	<-done
+
+	if fast, slow := sem.PathStats(); fast != 2 || slow != 1 {
+		t.Errorf("PathStats() = %d, %d; want 2, 1", fast, slow)
+	}
+}
+
+func TestWeightedShrink(t *testing.T) {
+	t.Parallel()
+
+	ctx := context.Background()
+	sem := semaphore.NewWeighted(10)
+	if err := sem.Shrink(ctx, 5); err != nil {
+		t.Fatalf("Shrink of an idle semaphore = %v", err)
+	}
+
+	sem.Acquire(ctx, 4)
+	sem.Acquire(ctx, 1)
+	shrunk := make(chan error, 1)
+	go func() { shrunk <- sem.Shrink(ctx, 2) }()
+	select {
+	case err := <-shrunk:
+		t.Fatalf("Shrink returned %v while 5 were held; want it to wait", err)
+	case <-time.After(10 * time.Millisecond):
+	}
+	sem.Release(1)
+	select {
+	case err := <-shrunk:
+		t.Fatalf("Shrink returned %v while 4 were held; want it to wait", err)
+	case <-time.After(10 * time.Millisecond):
+	}
+	sem.Release(4)
+	if err := <-shrunk; err != nil {
+		t.Fatalf("Shrink = %v", err)
+	}
+	if sem.TryAcquire(3) || !sem.TryAcquire(2) {
+		t.Error("semaphore does not have the new size after Shrink")
+	}
+
+	cctx, cancel := context.WithTimeout(ctx, 10*time.Millisecond)
+	defer cancel()
+	if err := sem.Shrink(cctx, 1); err != context.DeadlineExceeded {
+		t.Errorf("Shrink with holders that never release = %v; want %v", err, context.DeadlineExceeded)
+	}
+}
+
+func TestWeightedWaitIdle(t *testing.T) {
+	t.Parallel()
+
+	ctx := context.Background()
+	sem := semaphore.NewWeighted(3)
+	if err := sem.WaitIdle(ctx); err != nil {
+		t.Fatalf("WaitIdle on an idle semaphore = %v; want nil", err)
+	}
+
+	sem.Acquire(ctx, 1)
+	sem.Acquire(ctx, 2)
+	idle := make(chan error)
+	go func() { idle <- sem.WaitIdle(ctx) }()
+	sem.Release(2)
+	select {
+	case err := <-idle:
+		t.Fatalf("WaitIdle returned %v while weight was still held", err)
+	case <-time.After(10 * time.Millisecond):
+	}
+	sem.Release(1)
+	if err := <-idle; err != nil {
+		t.Errorf("WaitIdle = %v; want nil", err)
+	}
+	// WaitIdle held nothing.
+	if !sem.TryAcquire(3) {
+		t.Error("TryAcquire(3) failed after WaitIdle")
+	}
+
+	cctx, cancel := context.WithTimeout(ctx, 10*time.Millisecond)
+	defer cancel()
+	if err := sem.WaitIdle(cctx); err != context.DeadlineExceeded {
+		t.Errorf("WaitIdle past the deadline = %v; want %v", err, context.DeadlineExceeded)
+	}
+}
+
+func TestWeightedTryAcquireAll(t *testing.T) {
+	t.Parallel()
+
+	sem := semaphore.NewWeighted(3)
+	if !sem.TryAcquireAll() {
+		t.Fatal("TryAcquireAll() on an idle semaphore = false; want true")
+	}
+	if sem.TryAcquire(1) {
+		t.Error("TryAcquire(1) succeeded after TryAcquireAll")
+	}
+	sem.Release(3)
+
+	sem.Resize(5)
+	sem.TryAcquire(1)
+	if sem.TryAcquireAll() {
+		t.Error("TryAcquireAll() with a token held = true; want false")
+	}
+	sem.Release(1)
+	if !sem.TryAcquireAll() {
+		t.Fatal("TryAcquireAll() after Resize = false; want true")
+	}
+	if sem.TryAcquire(1) {
+		t.Error("TryAcquireAll after Resize left tokens unacquired")
+	}
+}
+
+func TestWeightedAcquireClassified(t *testing.T) {
+	t.Parallel()
+
+	sem := semaphore.NewWeighted(1)
+	if timedOut, err := sem.AcquireClassified(context.Background(), 1); timedOut || err != nil {
+		t.Fatalf("AcquireClassified = %t, %v; want false, nil", timedOut, err)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+	if timedOut, err := sem.AcquireClassified(ctx, 1); !timedOut || err != context.DeadlineExceeded {
+		t.Errorf("AcquireClassified past the deadline = %t, %v; want true, %v", timedOut, err, context.DeadlineExceeded)
+	}
+
+	ctx, cancel = context.WithCancel(context.Background())
+	cancel()
+	if timedOut, err := sem.AcquireClassified(ctx, 1); timedOut || err != context.Canceled {
+		t.Errorf("AcquireClassified canceled = %t, %v; want false, %v", timedOut, err, context.Canceled)
+	}
+}
+
+func TestWeightedSlowWaitWarning(t *testing.T) {
+	t.Parallel()
+
+	sem := semaphore.NewWeighted(2)
+	const threshold = 10 * time.Millisecond
+	warned := make(chan int64, 10)
+	sem.SetSlowWaitWarning(threshold, func(n int64, waited time.Duration) {
+		if waited < threshold {
+			t.Errorf("warned after waiting %v; want at least %v", waited, threshold)
+		}
+		warned <- n
+	})
+
+	ctx := context.Background()
+	sem.Acquire(ctx, 2)
+	done := make(chan struct{})
+	go func() {
+		sem.Acquire(ctx, 1)
+		close(done)
+	}()
+
+	select {
+	case n := <-warned:
+		if n != 1 {
+			t.Errorf("warned for a waiter of weight %d; want 1", n)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("no warning for a blocked waiter")
+	}
+	time.Sleep(5 * threshold)
+	sem.Release(2)
+	<-done
+
+	// A waiter served before the threshold is not reported.
+	done = make(chan struct{})
+	go func() {
+		sem.Acquire(ctx, 2)
+		close(done)
+	}()
+	waitForQueueLen(sem, 1)
+	sem.Release(1)
+	<-done
+	time.Sleep(2 * threshold)
+	if got := len(warned); got != 0 {
+		t.Errorf("%d more warnings; want 0", got)
+	}
+}
+
+func TestAcquireWithFallback(t *testing.T) {
+	t.Parallel()
+
+	ctx := context.Background()
+	primary := semaphore.NewWeighted(2)
+	secondary := semaphore.NewWeighted(2)
+
+	for i, want := range []*semaphore.Weighted{primary, primary, secondary, secondary} {
+		used, err := semaphore.AcquireWithFallback(ctx, primary, secondary, 1)
+		if err != nil || used != want {
+			t.Fatalf("AcquireWithFallback #%d = %p, %v; want %p, nil", i, used, err, want)
+		}
+	}
+
+	cctx, cancel := context.WithTimeout(ctx, 10*time.Millisecond)
+	defer cancel()
+	used, err := semaphore.AcquireWithFallback(cctx, primary, secondary, 1)
+	if used != nil || err != context.DeadlineExceeded {
+		t.Errorf("AcquireWithFallback with both full = %p, %v; want nil, %v", used, err, context.DeadlineExceeded)
+	}
+
+	// Releasing the primary makes it preferred again.
+	primary.Release(1)
+	if used, _ := semaphore.AcquireWithFallback(ctx, primary, secondary, 1); used != primary {
+		t.Error("AcquireWithFallback did not use the primary once it had room")
+	}
+}
@@ -0,0 +1,52 @@
+// Copyright 2026 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package semaphore
+
+import "context"
+
+// AcquireTagged is like Acquire, but attributes the acquired weight to tag
+// in the usage reported by UsageByTag. The weight must be released with
+// ReleaseTagged and the same tag.
+func (s *Weighted) AcquireTagged(ctx context.Context, n int64, tag string) error {
+	if err := s.Acquire(ctx, n); err != nil {
+		return err
+	}
+	s.mu.Lock()
+	if s.tags == nil {
+		s.tags = make(map[string]int64)
+	}
+	s.tags[tag] += n
+	s.mu.Unlock()
+	return nil
+}
+
+// ReleaseTagged releases a weight of n acquired with AcquireTagged and tag.
+func (s *Weighted) ReleaseTagged(n int64, tag string) {
+	s.mu.Lock()
+	held := s.tags[tag]
+	if held < n {
+		s.mu.Unlock()
+		panic("semaphore: released more than held for tag " + tag)
+	}
+	if held == n {
+		delete(s.tags, tag)
+	} else {
+		s.tags[tag] = held - n
+	}
+	s.mu.Unlock()
+	s.Release(n)
+}
+
+// UsageByTag returns the weight currently held under each tag by callers of
+// AcquireTagged. Tags that hold no weight are omitted.
+func (s *Weighted) UsageByTag() map[string]int64 {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	usage := make(map[string]int64, len(s.tags))
+	for tag, n := range s.tags {
+		usage[tag] = n
+	}
+	return usage
+}
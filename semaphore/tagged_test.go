@@ -0,0 +1,39 @@
+// Copyright 2026 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package semaphore_test
+
+import (
+	"context"
+	"reflect"
+	"testing"
+
+	"golang.org/x/sync/semaphore"
+)
+
+func TestWeightedUsageByTag(t *testing.T) {
+	t.Parallel()
+
+	ctx := context.Background()
+	sem := semaphore.NewWeighted(10)
+	sem.AcquireTagged(ctx, 2, "read")
+	sem.AcquireTagged(ctx, 3, "read")
+	sem.AcquireTagged(ctx, 4, "write")
+	sem.Acquire(ctx, 1) // Untagged.
+
+	want := map[string]int64{"read": 5, "write": 4}
+	if got := sem.UsageByTag(); !reflect.DeepEqual(got, want) {
+		t.Errorf("UsageByTag() = %v; want %v", got, want)
+	}
+
+	sem.ReleaseTagged(4, "write")
+	sem.ReleaseTagged(2, "read")
+	want = map[string]int64{"read": 3}
+	if got := sem.UsageByTag(); !reflect.DeepEqual(got, want) {
+		t.Errorf("UsageByTag() after releases = %v; want %v", got, want)
+	}
+	if !sem.TryAcquire(6) {
+		t.Error("tagged releases did not return weight to the semaphore")
+	}
+}
@@ -0,0 +1,69 @@
+// Copyright 2026 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package semaphore
+
+import (
+	"errors"
+	"sync/atomic"
+)
+
+// ErrTransferTooLarge is returned by TransferCapacity when the semaphore
+// giving up capacity is smaller than the capacity to transfer.
+var ErrTransferTooLarge = errors.New("semaphore: transfer exceeds the size of the source semaphore")
+
+// lastLockID is the last lock-order id assigned by (*Weighted).lockID.
+var lastLockID atomic.Uint64
+
+// lockID returns the id that orders s among the semaphores that
+// TransferCapacity locks together, assigning one on first use.
+func (s *Weighted) lockID() uint64 {
+	if id := s.id.Load(); id != 0 {
+		return id
+	}
+	s.id.CompareAndSwap(0, lastLockID.Add(1))
+	return s.id.Load()
+}
+
+// TransferCapacity moves n of the capacity of from to to, as if by
+// from.Resize(size-n) and to.Resize(size+n), but atomically: no observer of
+// either semaphore can see the capacity in both or in neither. Waiters on to
+// that now fit are woken. As with Resize, from may end up smaller than the
+// weight held from it, in which case new acquisitions from it cannot
+// succeed until enough weight has been released.
+//
+// If the size of from is less than n, TransferCapacity returns
+// ErrTransferTooLarge and changes neither semaphore. It panics if n is
+// negative.
+func TransferCapacity(from, to *Weighted, n int64) error {
+	if n < 0 {
+		panic("semaphore: negative capacity transfer")
+	}
+	if from == to {
+		from.mu.Lock()
+		defer from.mu.Unlock()
+		if from.size < n {
+			return ErrTransferTooLarge
+		}
+		return nil
+	}
+
+	// Lock the two semaphores in a global order, so that concurrent
+	// transfers in opposite directions cannot deadlock.
+	first, second := from, to
+	if second.lockID() < first.lockID() {
+		first, second = second, first
+	}
+	first.mu.Lock()
+	defer first.mu.Unlock()
+	second.mu.Lock()
+	defer second.mu.Unlock()
+
+	if from.size < n {
+		return ErrTransferTooLarge
+	}
+	from.resize(from.size - n)
+	to.resize(to.size + n)
+	return nil
+}
@@ -0,0 +1,71 @@
+// Copyright 2026 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package semaphore_test
+
+import (
+	"context"
+	"sync"
+	"testing"
+
+	"golang.org/x/sync/semaphore"
+)
+
+func sizeOf(s *semaphore.Weighted) int64 {
+	sink := make(gaugeSink)
+	s.Collector("").Collect(sink)
+	return int64(sink["capacity"])
+}
+
+func TestTransferCapacity(t *testing.T) {
+	t.Parallel()
+
+	ctx := context.Background()
+	from := semaphore.NewWeighted(5)
+	to := semaphore.NewWeighted(2)
+	from.Acquire(ctx, 4)
+	to.Acquire(ctx, 2)
+	acquired := make(chan struct{})
+	go func() {
+		to.Acquire(ctx, 2)
+		close(acquired)
+	}()
+	waitForQueueLen(to, 1)
+
+	// Taking from below the weight held is allowed.
+	if err := semaphore.TransferCapacity(from, to, 3); err != nil {
+		t.Fatalf("TransferCapacity = %v; want nil", err)
+	}
+	<-acquired
+	if gotFrom, gotTo := sizeOf(from), sizeOf(to); gotFrom != 2 || gotTo != 5 {
+		t.Errorf("sizes after the transfer = %d, %d; want 2, 5", gotFrom, gotTo)
+	}
+	if from.TryAcquire(1) {
+		t.Error("TryAcquire(1) succeeded on a semaphore shrunk below its held weight")
+	}
+
+	if err := semaphore.TransferCapacity(from, to, 3); err != semaphore.ErrTransferTooLarge {
+		t.Errorf("TransferCapacity beyond the size = %v; want %v", err, semaphore.ErrTransferTooLarge)
+	}
+	if gotFrom, gotTo := sizeOf(from), sizeOf(to); gotFrom != 2 || gotTo != 5 {
+		t.Errorf("sizes after a failed transfer = %d, %d; want 2, 5", gotFrom, gotTo)
+	}
+
+	// Concurrent transfers in both directions conserve the total capacity
+	// and do not deadlock.
+	var wg sync.WaitGroup
+	for _, pair := range [][2]*semaphore.Weighted{{from, to}, {to, from}} {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := 0; i < 1000; i++ {
+				semaphore.TransferCapacity(pair[0], pair[1], 1)
+			}
+		}()
+	}
+	wg.Wait()
+	if total := sizeOf(from) + sizeOf(to); total != 7 {
+		t.Errorf("total size after concurrent transfers = %d; want 7", total)
+	}
+}
@@ -0,0 +1,129 @@
+// Copyright 2026 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package singleflight
+
+import "time"
+
+// A BreakerConfig configures the circuit breaker of DoBreaker.
+type BreakerConfig struct {
+	// Threshold is the number of consecutive failed calls that opens the
+	// breaker. A Threshold < 1 is treated as 1.
+	Threshold int
+
+	// Window, if positive, is the time within which the failures must
+	// occur: a failure more than Window after the first of the current run
+	// of failures starts a new run.
+	Window time.Duration
+
+	// Cooldown is how long the breaker stays open before letting a single
+	// call through to probe whether fn has recovered.
+	Cooldown time.Duration
+}
+
+// A breaker is the state of the circuit breaker for a key; see DoBreaker.
+type breaker struct {
+	failures     int       // consecutive failures
+	firstFailure time.Time // of the current run of failures
+	open         bool
+	opened       time.Time // when the breaker last opened
+	probing      bool      // a half-open probe is in flight
+	err          error     // the last error, returned while open
+	pruned       bool      // removed from g.breakers after closing
+}
+
+// DoBreaker is like Do, but guards fn with a circuit breaker for key.
+//
+// The breaker starts closed, letting calls through. Once cfg.Threshold
+// consecutive calls through DoBreaker have failed, it opens: for
+// cfg.Cooldown, DoBreaker returns the last error, marked as shared, without
+// calling fn. After that, the breaker is half-open: the next call is let
+// through as a probe, and other calls keep getting the last error while it
+// is in flight. If the probe succeeds, the breaker closes; if it fails, the
+// breaker opens again for another cfg.Cooldown. A probe that joins a call
+// for key already in flight from another method, such as Do, takes that
+// call's outcome as its own.
+//
+// Forget resets the breaker for key to closed.
+func (g *Group) DoBreaker(key string, cfg BreakerConfig, fn func() (interface{}, error)) (v interface{}, err error, shared bool) {
+	g.mu.Lock()
+	b := g.breakerLocked(key)
+	probe := false
+	if b.open {
+		if b.probing || g.now().Sub(b.opened) < cfg.Cooldown {
+			err := b.err
+			g.mu.Unlock()
+			return nil, err, true
+		}
+		b.probing, probe = true, true
+	}
+	g.mu.Unlock()
+
+	// The outcome is recorded here rather than in the function passed to
+	// Do, which does not run if this call joins one already in flight.
+	ran, normalReturn := false, false
+	defer func() {
+		g.mu.Lock()
+		defer g.mu.Unlock()
+		if g.breakers[key] != b {
+			if !b.pruned {
+				return // Forgotten.
+			}
+			b = g.breakerLocked(key)
+		}
+		if probe {
+			b.probing = false
+		}
+		// Callers that merely joined another call leave recording its
+		// outcome to that call, so that it is counted once.
+		if normalReturn && (ran || probe) {
+			b.record(err, cfg, g.now())
+			if !b.open && b.failures == 0 {
+				b.pruned = true
+				delete(g.breakers, key)
+			}
+		}
+	}()
+	v, err, shared = g.Do(key, func() (interface{}, error) {
+		ran = true
+		return fn()
+	})
+	normalReturn = true
+	return v, err, shared
+}
+
+// breakerLocked returns the breaker for key, creating it if necessary.
+// g.mu must be held.
+func (g *Group) breakerLocked(key string) *breaker {
+	b := g.breakers[key]
+	if b == nil {
+		if g.breakers == nil {
+			g.breakers = make(map[string]*breaker)
+		}
+		b = new(breaker)
+		g.breakers[key] = b
+	}
+	return b
+}
+
+// record updates b with the outcome of a call that completed at now.
+func (b *breaker) record(err error, cfg BreakerConfig, now time.Time) {
+	if err == nil {
+		*b = breaker{}
+		return
+	}
+	b.err = err
+	if b.open {
+		// The probe failed.
+		b.opened = now
+		return
+	}
+	if b.failures == 0 || cfg.Window > 0 && now.Sub(b.firstFailure) > cfg.Window {
+		b.failures, b.firstFailure = 0, now
+	}
+	b.failures++
+	if b.failures >= max(cfg.Threshold, 1) {
+		b.open, b.opened = true, now
+	}
+}
@@ -0,0 +1,102 @@
+// Copyright 2026 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package singleflight
+
+import (
+	"errors"
+	"testing"
+	"time"
+)
+
+func TestDoBreaker(t *testing.T) {
+	var g Group
+	clock := newFakeClock()
+	g.setClock(clock.Now)
+	cfg := BreakerConfig{Threshold: 2, Window: time.Minute, Cooldown: 10 * time.Second}
+
+	errDown := errors.New("backend down")
+	calls := 0
+	fail := func() (interface{}, error) {
+		calls++
+		return nil, errDown
+	}
+	succeed := func() (interface{}, error) {
+		calls++
+		return "ok", nil
+	}
+	do := func(fn func() (interface{}, error), wantCalls int, wantErr error) {
+		t.Helper()
+		calls = 0
+		_, err, _ := g.DoBreaker("key", cfg, fn)
+		if calls != wantCalls || err != wantErr {
+			t.Fatalf("DoBreaker called fn %d times and returned %v; want %d and %v", calls, err, wantCalls, wantErr)
+		}
+	}
+
+	// Closed: failures spread beyond the window do not open the breaker.
+	do(fail, 1, errDown)
+	clock.Advance(2 * time.Minute)
+	do(fail, 1, errDown)
+
+	// Closed to open.
+	do(fail, 1, errDown)
+	do(succeed, 0, errDown)
+	clock.Advance(cfg.Cooldown - 1)
+	do(succeed, 0, errDown)
+
+	// Half-open: a failed probe opens the breaker again.
+	clock.Advance(1)
+	do(fail, 1, errDown)
+	do(succeed, 0, errDown)
+
+	// Half-open: other calls are short-circuited while the probe is in
+	// flight, and a successful probe closes the breaker.
+	clock.Advance(cfg.Cooldown)
+	do(func() (interface{}, error) {
+		do(succeed, 0, errDown)
+		calls++
+		return "ok", nil
+	}, 1, nil)
+	do(fail, 1, errDown)
+	do(succeed, 1, nil)
+
+	// Forget resets the breaker.
+	do(fail, 1, errDown)
+	do(fail, 1, errDown)
+	g.Forget("key")
+	do(succeed, 1, nil)
+
+	// A breaker closed by a success is pruned.
+	g.mu.Lock()
+	n := len(g.breakers)
+	g.mu.Unlock()
+	if n != 0 {
+		t.Errorf("len(g.breakers) = %d after a success; want 0", n)
+	}
+
+	// Half-open: a probe that joins a call in flight from Do takes its
+	// outcome.
+	do(fail, 1, errDown)
+	do(fail, 1, errDown)
+	clock.Advance(cfg.Cooldown)
+	started, release := make(chan struct{}), make(chan struct{})
+	go g.Do("key", func() (interface{}, error) {
+		close(started)
+		<-release
+		return "ok", nil
+	})
+	<-started
+	probed := make(chan error)
+	go func() {
+		_, err, _ := g.DoBreaker("key", cfg, fail)
+		probed <- err
+	}()
+	waitForDups(&g, "key", 1)
+	close(release)
+	if err := <-probed; err != nil {
+		t.Fatalf("DoBreaker joining a successful Do call = %v; want nil", err)
+	}
+	do(succeed, 1, nil)
+}
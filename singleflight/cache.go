@@ -0,0 +1,207 @@
+// Copyright 2026 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package singleflight
+
+import (
+	"container/list"
+	"math/rand/v2"
+	"time"
+)
+
+// An entry is the retained result of a completed call.
+type entry struct {
+	key  string
+	val  interface{}
+	err  error
+	size int64
+	elem *list.Element // position in Group.lru
+
+	expires time.Time // zero if the entry does not expire; see Set
+}
+
+// SetMaxBytes makes g memoize results: once a call completes successfully,
+// its result is retained and returned to later callers for the same key,
+// marked as shared, without calling fn again, until the key is forgotten or
+// the result is evicted.
+//
+// Retained results are bounded by a total size of n bytes, as measured by the
+// function set with SetSizeFunc. When the bound is exceeded, the least
+// recently used results are evicted. A result larger than n on its own is not
+// retained. An n <= 0 disables memoization and discards all retained results.
+func (g *Group) SetMaxBytes(n int64) {
+	g.mu.Lock()
+	g.maxBytes = n
+	g.evict()
+	g.unlock()
+}
+
+// SetSizeFunc sets the function used to measure the size in bytes of a
+// retained result for SetMaxBytes. The default counts every result as one
+// byte, so that the bound is on the number of results.
+//
+// size is called without g's lock held, so it may call methods of g.
+func (g *Group) SetSizeFunc(size func(interface{}) int64) {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	g.sizeFunc = size
+}
+
+// Snapshot returns a copy of the results retained by g, by key. Calls still
+// in flight and expired results are not included. Each Result is marked as
+// shared, as it would be if returned to a caller.
+func (g *Group) Snapshot() map[string]Result {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	m := make(map[string]Result, len(g.cache))
+	now := g.now()
+	for key, e := range g.cache {
+		if e.expires.IsZero() || now.Before(e.expires) {
+			m[key] = Result{e.val, e.err, true}
+		}
+	}
+	return m
+}
+
+// SetOnEvict sets a function to be called with the key and value of each
+// retained result when it leaves the cache, whether it was evicted to make
+// room for other results, replaced by a newer result for the same key,
+// forgotten with Forget, or discarded by SetMaxBytes. This allows results
+// that own resources to release them. A result that has expired (see Set)
+// is reported when a call for its key discovers the expiry.
+//
+// f is called without g's lock held, so it may call methods of g. A nil f
+// disables the callback.
+func (g *Group) SetOnEvict(f func(key string, v interface{})) {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	g.onEvict = f
+}
+
+// unlock unlocks g.mu and then reports the results discarded while it was
+// held to the function set with SetOnEvict.
+func (g *Group) unlock() {
+	evicted, f := g.evicted, g.onEvict
+	g.evicted = nil
+	g.mu.Unlock()
+	for _, e := range evicted {
+		f(e.key, e.val)
+	}
+}
+
+// Set retains v and err as the result for key, as if a call for key had
+// just returned them, so that later calls for key return them without
+// calling fn until the result is forgotten, evicted, or, if ttl > 0, ttl has
+// elapsed. Set replaces any result already retained for key. It has no
+// effect if memoization is disabled; see SetMaxBytes.
+//
+// A call for key already in flight is unaffected and still returns its own
+// result to its callers; when it completes, its result replaces the one
+// given to Set.
+func (g *Group) Set(key string, v interface{}, err error, ttl time.Duration) {
+	size := g.sizeOf(v)
+	g.mu.Lock()
+	defer g.unlock()
+	e := g.retain(key, v, err, size)
+	if e != nil && ttl > 0 {
+		if g.jitter > 0 {
+			ttl -= time.Duration(rand.Float64() * g.jitter * float64(ttl))
+		}
+		e.expires = g.now().Add(ttl)
+	}
+}
+
+// SetTTLJitter makes Set shorten each ttl by a random amount of up to
+// fraction of it, so that results set at the same time with the same ttl do
+// not all expire at once, causing their keys to be recomputed together.
+// The fraction is clamped to the range [0, 1]; 0, the default, disables
+// the jitter.
+func (g *Group) SetTTLJitter(fraction float64) {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	g.jitter = min(max(fraction, 0), 1)
+}
+
+// lookup returns the retained result for key, if any, discarding it
+// instead if it has expired, or else the result of a call for key within its
+// grace period.
+// g.mu must be held, and released with g.unlock.
+func (g *Group) lookup(key string) (*entry, bool) {
+	e, ok := g.cache[key]
+	if !ok {
+		return g.recentResult(key)
+	}
+	if !e.expires.IsZero() && !g.now().Before(e.expires) {
+		g.discard(key)
+		e, ok := g.recentResult(key)
+		g.pruneFamily(key)
+		return e, ok
+	}
+	g.lru.MoveToFront(e.elem)
+	return e, true
+}
+
+// sizeOf measures val for retain with the function set with SetSizeFunc,
+// or returns -1 without measuring it if memoization is disabled.
+// g.mu must not be held, as the function may call methods of g.
+func (g *Group) sizeOf(val interface{}) int64 {
+	g.mu.Lock()
+	size, enabled := g.sizeFunc, g.maxBytes > 0
+	g.mu.Unlock()
+	switch {
+	case !enabled:
+		return -1
+	case size == nil:
+		return 1
+	}
+	return size(val)
+}
+
+// retain retains the result of a completed call for key, of the given size
+// as measured by sizeOf, if memoization is enabled and the result fits, and
+// returns the new entry, or nil if the result was not retained.
+// g.mu must be held, and released with g.unlock.
+func (g *Group) retain(key string, val interface{}, err error, size int64) *entry {
+	if g.maxBytes <= 0 || size < 0 || size > g.maxBytes {
+		return nil
+	}
+
+	g.discard(key)
+	if g.cache == nil {
+		g.cache = make(map[string]*entry)
+	}
+	e := &entry{key: key, val: val, err: err, size: size}
+	e.elem = g.lru.PushFront(e)
+	g.cache[key] = e
+	g.bytes += size
+	g.evict()
+	return g.cache[key]
+}
+
+// discard drops the retained result for key, reporting whether there was one.
+// g.mu must be held, and released with g.unlock.
+func (g *Group) discard(key string) bool {
+	e, ok := g.cache[key]
+	if !ok {
+		return false
+	}
+	g.lru.Remove(e.elem)
+	delete(g.cache, key)
+	g.bytes -= e.size
+	if g.onEvict != nil {
+		g.evicted = append(g.evicted, e)
+	}
+	return true
+}
+
+// evict discards the least recently used results until the retained
+// results fit within g.maxBytes.
+// g.mu must be held.
+func (g *Group) evict() {
+	for g.lru.Len() > 0 && (g.maxBytes <= 0 || g.bytes > g.maxBytes) {
+		key := g.lru.Back().Value.(*entry).key
+		g.discard(key)
+		g.pruneFamily(key)
+	}
+}
@@ -0,0 +1,326 @@
+// Copyright 2026 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package singleflight
+
+import (
+	"errors"
+	"reflect"
+	"runtime"
+	"strconv"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestMemoize(t *testing.T) {
+	var g Group
+	g.SetMaxBytes(10)
+
+	calls := 0
+	fn := func() (interface{}, error) {
+		calls++
+		return calls, nil
+	}
+	if v, _, shared := g.Do("key", fn); v != 1 || shared {
+		t.Errorf("first Do = %v, shared %t; want 1, false", v, shared)
+	}
+	if v, _, shared := g.Do("key", fn); v != 1 || !shared {
+		t.Errorf("second Do = %v, shared %t; want retained 1, true", v, shared)
+	}
+	if r := <-g.DoChan("key", fn); r.Val != 1 || !r.Shared {
+		t.Errorf("DoChan = %+v; want retained 1, shared", r)
+	}
+
+	if !g.ForgetReport("key") {
+		t.Error("ForgetReport of a retained key = false; want true")
+	}
+	if v, _, _ := g.Do("key", fn); v != 2 {
+		t.Errorf("Do after Forget = %v; want 2", v)
+	}
+}
+
+func TestMemoizeSkipsErrors(t *testing.T) {
+	var g Group
+	g.SetMaxBytes(10)
+
+	calls := 0
+	fn := func() (interface{}, error) {
+		calls++
+		return nil, errors.New("failed")
+	}
+	g.Do("key", fn)
+	g.Do("key", fn)
+	if calls != 2 {
+		t.Errorf("fn called %d times; want 2, as errors are not retained", calls)
+	}
+}
+
+func TestSizeFuncReentrant(t *testing.T) {
+	var g Group
+	g.SetMaxBytes(10)
+	g.SetSizeFunc(func(v interface{}) int64 {
+		// A size function may use g, which would deadlock if it were
+		// called with g's lock held.
+		g.Forget("other")
+		g.Do("other", func() (interface{}, error) { return nil, errors.New("not retained") })
+		return 1
+	})
+
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		g.Do("key", func() (interface{}, error) { return "v", nil })
+		g.Set("set", "v", nil, 0)
+		g.DoFanout("primary", func() (map[string]interface{}, error) {
+			return map[string]interface{}{"fanned": "v"}, nil
+		})
+	}()
+	select {
+	case <-done:
+	case <-time.After(5 * time.Second):
+		t.Fatal("a size function that calls methods of g deadlocked")
+	}
+	for _, key := range []string{"key", "set", "fanned", "primary"} {
+		if _, ok := g.Snapshot()[key]; !ok {
+			t.Errorf("result for %q not retained", key)
+		}
+	}
+}
+
+func TestMaxBytes(t *testing.T) {
+	var g Group
+	g.SetSizeFunc(func(v interface{}) int64 {
+		return int64(len(v.(string)))
+	})
+	g.SetMaxBytes(10)
+
+	do := func(key, v string) {
+		g.Do(key, func() (interface{}, error) { return v, nil })
+	}
+	retained := func() map[string]bool {
+		g.mu.Lock()
+		defer g.mu.Unlock()
+		m := make(map[string]bool)
+		for k := range g.cache {
+			m[k] = true
+		}
+		if g.bytes > g.maxBytes {
+			t.Errorf("retained %d bytes; want at most %d", g.bytes, g.maxBytes)
+		}
+		return m
+	}
+
+	do("a", "aaaa")
+	do("b", "bbbb")
+	do("a", "")             // a is now more recently used than b
+	do("c", "cccc")         // evicts b, the least recently used
+	do("d", "dddddddddddd") // too large to retain at all
+	if got := retained(); len(got) != 2 || !got["a"] || !got["c"] {
+		t.Errorf("retained %v; want a and c", got)
+	}
+
+	g.SetMaxBytes(5)
+	if got := retained(); len(got) != 1 || !got["c"] {
+		t.Errorf("after shrinking the budget retained %v; want c", got)
+	}
+}
+
+func TestOnEvict(t *testing.T) {
+	var g Group
+	g.SetMaxBytes(2)
+	var evicted []string
+	g.SetOnEvict(func(key string, v interface{}) {
+		// The callback runs without g's lock held.
+		g.ForgetReport("unknown")
+		evicted = append(evicted, key+"="+v.(string))
+	})
+	check := func(what string, want ...string) {
+		t.Helper()
+		if strings.Join(evicted, ",") != strings.Join(want, ",") {
+			t.Errorf("after %s, evicted %q; want %q", what, evicted, want)
+		}
+		evicted = nil
+	}
+	do := func(key string) {
+		g.Do(key, func() (interface{}, error) {
+			return "v" + key, nil
+		})
+	}
+
+	do("a")
+	do("b")
+	check("filling the cache")
+	do("c")
+	check("exceeding the budget", "a=va")
+	g.Forget("b")
+	check("Forget", "b=vb")
+	do("d")
+	g.SetMaxBytes(1)
+	check("shrinking the budget", "c=vc")
+	g.SetMaxBytes(0)
+	check("disabling memoization", "d=vd")
+}
+
+func TestSnapshot(t *testing.T) {
+	var g Group
+	g.SetMaxBytes(10)
+	for _, key := range []string{"a", "b", "c"} {
+		g.Do(key, func() (interface{}, error) {
+			return "v" + key, nil
+		})
+	}
+	g.Do("failed", func() (interface{}, error) {
+		return nil, errors.New("not retained")
+	})
+	started := make(chan struct{})
+	unblock := make(chan struct{})
+	defer close(unblock)
+	go g.Do("in-flight", func() (interface{}, error) {
+		close(started)
+		<-unblock
+		return "", nil
+	})
+	<-started
+
+	got := g.Snapshot()
+	want := map[string]Result{
+		"a": {"va", nil, true},
+		"b": {"vb", nil, true},
+		"c": {"vc", nil, true},
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("Snapshot() = %v; want %v", got, want)
+	}
+}
+
+func TestSet(t *testing.T) {
+	var g Group
+	clock := newFakeClock()
+	g.setClock(clock.Now)
+	g.SetMaxBytes(10)
+	var evicted []string
+	g.SetOnEvict(func(key string, v interface{}) {
+		evicted = append(evicted, key)
+	})
+
+	calls := 0
+	fn := func() (interface{}, error) {
+		calls++
+		return "computed", nil
+	}
+	g.Set("key", "seeded", nil, time.Minute)
+	if v, err, shared := g.Do("key", fn); v != "seeded" || err != nil || !shared {
+		t.Errorf("Do after Set = %v, %v, %t; want %q, nil, true", v, err, shared, "seeded")
+	}
+	clock.Advance(time.Minute - 1)
+	if v, _, _ := g.Do("key", fn); v != "seeded" {
+		t.Errorf("Do just before expiry = %v; want %q", v, "seeded")
+	}
+	if calls != 0 {
+		t.Errorf("fn called %d times while a seeded result was retained; want 0", calls)
+	}
+
+	clock.Advance(1)
+	if len(g.Snapshot()) != 0 {
+		t.Error("Snapshot includes an expired result")
+	}
+	if v, _, _ := g.Do("key", fn); v != "computed" || calls != 1 {
+		t.Errorf("Do after expiry = %v with %d calls; want %q with 1", v, calls, "computed")
+	}
+	if len(evicted) != 1 || evicted[0] != "key" {
+		t.Errorf("evicted %q; want the expired key", evicted)
+	}
+
+	// Errors can be seeded too, and without a ttl they do not expire.
+	seeded := errors.New("seeded")
+	g.Set("err", nil, seeded, 0)
+	clock.Advance(24 * time.Hour)
+	if _, err, _ := g.Do("err", fn); err != seeded {
+		t.Errorf("Do after seeding an error = %v; want %v", err, seeded)
+	}
+}
+
+func TestSetTTLJitter(t *testing.T) {
+	var g Group
+	clock := newFakeClock()
+	g.setClock(clock.Now)
+	g.SetMaxBytes(1000)
+	g.SetTTLJitter(0.5)
+
+	const (
+		n   = 1000
+		ttl = 100 * time.Second
+	)
+	for i := 0; i < n; i++ {
+		g.Set(strconv.Itoa(i), i, nil, ttl)
+	}
+
+	// The expirations are spread over the last half of ttl: every one falls
+	// within it, and each of its ten slices gets a fair share.
+	var buckets [10]int
+	for _, e := range g.cache {
+		d := e.expires.Sub(clock.Now())
+		if d < ttl/2 || d > ttl {
+			t.Fatalf("result expires after %v; want between %v and %v", d, ttl/2, ttl)
+		}
+		buckets[min(int((d-ttl/2)*10/(ttl/2)), 9)]++
+	}
+	for i, c := range buckets {
+		if c < n/10/2 {
+			t.Errorf("%d expirations in slice %d of the jitter window; want about %d: %v", c, i, n/10, buckets)
+		}
+	}
+
+	// Without jitter, they all expire at once.
+	g.SetTTLJitter(0)
+	g.Set("fixed", 0, nil, ttl)
+	if d := g.cache["fixed"].expires.Sub(clock.Now()); d != ttl {
+		t.Errorf("result without jitter expires after %v; want %v", d, ttl)
+	}
+}
+
+func TestForgetDuringCall(t *testing.T) {
+	for i := 0; i < 200; i++ {
+		var g Group
+		g.SetMaxBytes(10)
+		var calls int32
+		fn := func() (interface{}, error) {
+			atomic.AddInt32(&calls, 1)
+			return "v", nil
+		}
+		release := make(chan struct{})
+		slow := func() (interface{}, error) {
+			<-release
+			return fn()
+		}
+
+		done := make(chan struct{})
+		go func() {
+			g.Do("key", slow)
+			close(done)
+		}()
+		for waitForDups(&g, "key", 0) == nil {
+			runtime.Gosched()
+		}
+		forgot := make(chan struct{})
+		go func() {
+			// Vary where Forget lands relative to the completion of the call.
+			for j := 0; j < i%10; j++ {
+				runtime.Gosched()
+			}
+			g.Forget("key")
+			close(forgot)
+		}()
+		close(release)
+		<-done
+		<-forgot
+
+		g.Do("key", fn)
+		if n := atomic.LoadInt32(&calls); n != 2 {
+			t.Fatalf("iteration %d: fn called %d times; want the call after Forget to run fn", i, n)
+		}
+	}
+}
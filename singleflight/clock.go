@@ -0,0 +1,22 @@
+// Copyright 2026 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package singleflight
+
+import "time"
+
+// now returns the current time according to g's clock.
+func (g *Group) now() time.Time {
+	if g.clock != nil {
+		return g.clock()
+	}
+	return time.Now()
+}
+
+// setClock makes g read the time from now rather than time.Now, so that
+// tests can control the passage of time for time-based features.
+// It must be called before g is used.
+func (g *Group) setClock(now func() time.Time) {
+	g.clock = now
+}
@@ -0,0 +1,126 @@
+// Copyright 2026 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package singleflight
+
+import (
+	"strconv"
+	"sync"
+	"testing"
+	"time"
+)
+
+// A fakeClock is a clock for tests that only moves when advanced.
+type fakeClock struct {
+	mu  sync.Mutex
+	now time.Time
+}
+
+func newFakeClock() *fakeClock {
+	return &fakeClock{now: time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC)}
+}
+
+func (c *fakeClock) Now() time.Time {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.now
+}
+
+func (c *fakeClock) Advance(d time.Duration) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.now = c.now.Add(d)
+}
+
+func TestDoThrottledBoundary(t *testing.T) {
+	var g Group
+	clock := newFakeClock()
+	g.setClock(clock.Now)
+
+	const interval = time.Minute
+	calls := 0
+	fn := func() (interface{}, error) {
+		calls++
+		return calls, nil
+	}
+	g.DoThrottled("key", interval, fn)
+
+	clock.Advance(interval - 1)
+	if v, _, shared := g.DoThrottled("key", interval, fn); v != 1 || !shared {
+		t.Errorf("DoThrottled just before the interval = %v, shared %t; want 1, true", v, shared)
+	}
+	clock.Advance(1)
+	if v, _, shared := g.DoThrottled("key", interval, fn); v != 2 || shared {
+		t.Errorf("DoThrottled at the interval = %v, shared %t; want 2, false", v, shared)
+	}
+}
+
+func TestDoThrottledForgetInFlight(t *testing.T) {
+	var g Group
+	started, unblock := make(chan struct{}), make(chan struct{})
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		g.DoThrottled("key", time.Hour, func() (interface{}, error) {
+			close(started)
+			<-unblock
+			return "stale", nil
+		})
+	}()
+	<-started
+	g.Forget("key")
+	close(unblock)
+	<-done
+
+	v, _, shared := g.DoThrottled("key", time.Hour, func() (interface{}, error) {
+		return "fresh", nil
+	})
+	if v != "fresh" || shared {
+		t.Errorf("DoThrottled after Forget during a call = %v, shared %t; want fresh, false", v, shared)
+	}
+}
+
+func TestDoThrottledDiscardsStale(t *testing.T) {
+	var g Group
+	clock := newFakeClock()
+	g.setClock(clock.Now)
+
+	const interval = time.Minute
+	fn := func() (interface{}, error) { return nil, nil }
+	for i := 0; i < 100; i++ {
+		g.DoThrottled(strconv.Itoa(i), interval, fn)
+	}
+	clock.Advance(interval)
+
+	// Stale results are swept as calls complete.
+	for i := 100; i < 200; i++ {
+		g.DoThrottled(strconv.Itoa(i), interval, fn)
+	}
+	g.mu.Lock()
+	n := len(g.throttled)
+	g.mu.Unlock()
+	if n > 100 {
+		t.Errorf("len(g.throttled) = %d after the first 100 results went stale; want at most 100", n)
+	}
+}
+
+func TestDurationBucketsClock(t *testing.T) {
+	var g Group
+	clock := newFakeClock()
+	g.setClock(clock.Now)
+	g.SetDurationBuckets([]time.Duration{time.Second})
+
+	g.Do("key", func() (interface{}, error) {
+		clock.Advance(time.Second)
+		return nil, nil
+	})
+	g.Do("key", func() (interface{}, error) {
+		clock.Advance(time.Second + 1)
+		return nil, nil
+	})
+	b := g.Stats().DurationBuckets
+	if len(b) != 2 || b[0].Count != 1 || b[1].Count != 1 {
+		t.Errorf("DurationBuckets = %+v; want one call in each bucket", b)
+	}
+}
@@ -0,0 +1,297 @@
+// Copyright 2026 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package singleflight
+
+import (
+	"context"
+	"sync"
+	"time"
+)
+
+// DoContext is like Do, but each caller stops waiting when its ctx is done,
+// returning ctx.Err(), and fn runs on a goroutine of its own with a Context
+// that is canceled once every DoContext caller waiting for it has given up.
+// Callers that join the call through Do, DoFIFO, or DoChan keep fn's Context
+// alive until the call completes.
+//
+// When fn's Context is canceled because all its callers gave up, the key is
+// forgotten, so the next call for key starts afresh instead of joining a call
+// that nobody is waiting for, and the result of the abandoned call is
+// discarded.
+//
+// fn's Context carries the values of the ctx of the caller that started
+// the call.
+func (g *Group) DoContext(ctx context.Context, key string, fn func(context.Context) (interface{}, error)) (v interface{}, err error, shared bool) {
+	g.mu.Lock()
+	if g.m == nil {
+		g.m = make(map[string]*call)
+	}
+	if e, ok := g.lookup(key); ok {
+		g.unlock()
+		return e.val, e.err, true
+	}
+	if c, ok := g.m[key]; ok {
+		c.dups++
+		c.refs++
+		c.shared.add(ctx.Deadline())
+		if c.done == nil {
+			c.done = make(chan struct{})
+		}
+		g.unlock()
+		return g.wait(ctx, c, key, true)
+	}
+	fnCtx, cancel := context.WithCancel(context.WithoutCancel(ctx))
+	c := &call{
+		done:     make(chan struct{}),
+		detached: true,
+		refs:     1,
+		cancel:   cancel,
+		ctx:      fnCtx,
+	}
+	c.wg.Add(1)
+	g.register(key, c)
+	g.unlock()
+
+	go g.doCall(c, key, func() (interface{}, error) {
+		return fn(fnCtx)
+	})
+	return g.wait(ctx, c, key, false)
+}
+
+// wait waits for c to complete or for ctx to be done, on behalf of a caller
+// that holds a reference to c.
+func (g *Group) wait(ctx context.Context, c *call, key string, dup bool) (v interface{}, err error, shared bool) {
+	select {
+	case <-c.done:
+	case <-ctx.Done():
+		g.mu.Lock()
+		select {
+		case <-c.done:
+			// The call completed after we were canceled. Rather than
+			// discarding its result, pretend we didn't notice the cancelation.
+			g.mu.Unlock()
+		default:
+			c.shared.remove(ctx.Deadline())
+			g.release(c, key)
+			g.mu.Unlock()
+			return nil, ctx.Err(), false
+		}
+	}
+
+	v, err = c.result()
+	return v, err, dup || c.dups > 0
+}
+
+// release drops a caller's reference to the in-flight call c. When no
+// callers remain, it cancels fn's Context and forgets key.
+// g.mu must be held.
+func (g *Group) release(c *call, key string) {
+	c.refs--
+	if c.refs > 0 || c.cancel == nil {
+		return
+	}
+	c.cancel()
+	c.forgotten = true
+	if g.m[key] == c {
+		delete(g.m, key)
+	}
+}
+
+// DoChanContext is like DoChan, but each caller's channel receives a Result
+// carrying ctx.Err() as soon as its ctx is done, and fn runs with a Context
+// whose deadline is the latest of the deadlines of the callers still waiting
+// for the result. Callers without a deadline, including those that join the
+// call through Do, DoFIFO, or DoChan, leave fn's Context without a deadline.
+// As callers give up, the deadline shrinks to that of the callers that remain;
+// once every caller has given up, fn's Context is canceled and key is
+// forgotten, as for DoContext.
+//
+// When the deadline of fn's Context passes, its Err method returns
+// context.DeadlineExceeded.
+//
+// The returned channel will not be closed.
+func (g *Group) DoChanContext(ctx context.Context, key string, fn func(context.Context) (interface{}, error)) <-chan Result {
+	ch := make(chan Result, 1)
+	g.mu.Lock()
+	defer g.unlock()
+	if g.m == nil {
+		g.m = make(map[string]*call)
+	}
+	if e, ok := g.lookup(key); ok {
+		ch <- Result{e.val, e.err, true}
+		return ch
+	}
+	if c, ok := g.m[key]; ok {
+		c.dups++
+		c.refs++
+		c.shared.add(ctx.Deadline())
+		g.subscribe(ctx, c, key, ch)
+		return ch
+	}
+	shared := newSharedContext(ctx)
+	shared.add(ctx.Deadline())
+	c := &call{
+		detached: true,
+		refs:     1,
+		cancel:   shared.close,
+		shared:   shared,
+		ctx:      shared,
+	}
+	c.wg.Add(1)
+	g.register(key, c)
+	g.subscribe(ctx, c, key, ch)
+
+	go g.doCall(c, key, func() (interface{}, error) {
+		return fn(shared)
+	})
+	return ch
+}
+
+// A subscriber is a DoChanContext caller waiting for a call to complete.
+type subscriber struct {
+	ch   chan<- Result
+	stop func() bool // stops the ctx.Done watch
+	left bool        // a Result has been sent on ch
+}
+
+// subscribe adds a DoChanContext caller with channel ch to the in-flight
+// call c, arranging for the caller to give up when ctx is done.
+// g.mu must be held.
+func (g *Group) subscribe(ctx context.Context, c *call, key string, ch chan<- Result) {
+	sub := &subscriber{ch: ch}
+	c.subs = append(c.subs, sub)
+	sub.stop = context.AfterFunc(ctx, func() {
+		g.mu.Lock()
+		defer g.mu.Unlock()
+		if sub.left {
+			return
+		}
+		sub.left = true
+		c.shared.remove(ctx.Deadline())
+		g.release(c, key)
+		ch <- Result{Err: ctx.Err()}
+	})
+}
+
+// A sharedContext is the Context passed to fn by DoChanContext. Its deadline
+// is the latest of the deadlines added to it, or none if any caller without
+// a deadline was added.
+type sharedContext struct {
+	context.Context // canceled with cause context.DeadlineExceeded once the deadline passes
+	cancel          context.CancelCauseFunc
+
+	mu        sync.Mutex
+	unbounded int               // callers without a deadline
+	deadlines map[time.Time]int // deadline to the number of callers with it
+	deadline  time.Time         // zero if there is none
+	timer     *time.Timer
+}
+
+func newSharedContext(parent context.Context) *sharedContext {
+	ctx, cancel := context.WithCancelCause(context.WithoutCancel(parent))
+	return &sharedContext{
+		Context:   ctx,
+		cancel:    cancel,
+		deadlines: make(map[time.Time]int),
+	}
+}
+
+func (s *sharedContext) Deadline() (deadline time.Time, ok bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.deadline, !s.deadline.IsZero()
+}
+
+func (s *sharedContext) Err() error {
+	err := s.Context.Err()
+	if err != nil && context.Cause(s.Context) == context.DeadlineExceeded {
+		return context.DeadlineExceeded
+	}
+	return err
+}
+
+// add records a caller with the given deadline; ok is false if the caller
+// has none. add is a no-op on a nil *sharedContext.
+func (s *sharedContext) add(deadline time.Time, ok bool) {
+	if s == nil {
+		return
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if ok {
+		s.deadlines[deadline]++
+	} else {
+		s.unbounded++
+	}
+	s.update()
+}
+
+// remove forgets a caller recorded by add.
+func (s *sharedContext) remove(deadline time.Time, ok bool) {
+	if s == nil {
+		return
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if !ok {
+		s.unbounded--
+	} else if s.deadlines[deadline]--; s.deadlines[deadline] == 0 {
+		delete(s.deadlines, deadline)
+	}
+	s.update()
+}
+
+// update recomputes the deadline and rearms the timer that enforces it.
+// s.mu must be held.
+func (s *sharedContext) update() {
+	if s.unbounded == 0 && len(s.deadlines) == 0 {
+		// The last caller has left; keep the deadline so that close can
+		// tell whether it passed.
+		return
+	}
+	var deadline time.Time
+	if s.unbounded == 0 {
+		for d := range s.deadlines {
+			if d.After(deadline) {
+				deadline = d
+			}
+		}
+	}
+	if deadline.Equal(s.deadline) {
+		return
+	}
+	s.deadline = deadline
+	if s.timer != nil {
+		s.timer.Stop()
+		s.timer = nil
+	}
+	if deadline.IsZero() {
+		return
+	}
+	dur := time.Until(deadline)
+	if dur <= 0 {
+		s.cancel(context.DeadlineExceeded)
+		return
+	}
+	s.timer = time.AfterFunc(dur, func() {
+		s.cancel(context.DeadlineExceeded)
+	})
+}
+
+// close cancels s and releases its timer. If the deadline has passed, s is
+// canceled with cause context.DeadlineExceeded.
+func (s *sharedContext) close() {
+	s.mu.Lock()
+	if s.timer != nil {
+		s.timer.Stop()
+		s.timer = nil
+	}
+	cause := context.Canceled
+	if !s.deadline.IsZero() && !time.Now().Before(s.deadline) {
+		cause = context.DeadlineExceeded
+	}
+	s.mu.Unlock()
+	s.cancel(cause)
+}
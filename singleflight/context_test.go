@@ -0,0 +1,240 @@
+// Copyright 2026 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package singleflight
+
+import (
+	"context"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestDoContext(t *testing.T) {
+	var g Group
+	v, err, shared := g.DoContext(context.Background(), "key", func(context.Context) (interface{}, error) {
+		return "bar", nil
+	})
+	if v != "bar" || err != nil || shared {
+		t.Errorf("DoContext = %v, %v, %t; want %q, nil, false", v, err, shared, "bar")
+	}
+}
+
+func TestDoContextForgetsAbandonedCall(t *testing.T) {
+	var g Group
+	var calls int32
+	started := make(chan struct{})
+	fnCanceled := make(chan struct{})
+	fn := func(ctx context.Context) (interface{}, error) {
+		if atomic.AddInt32(&calls, 1) == 1 {
+			close(started)
+			<-ctx.Done()
+			close(fnCanceled)
+			return "stale", nil
+		}
+		return "fresh", nil
+	}
+
+	const n = 3
+	ctx, cancel := context.WithCancel(context.Background())
+	errs := make(chan error, n)
+	for i := 0; i < n; i++ {
+		go func() {
+			_, err, _ := g.DoContext(ctx, "key", fn)
+			errs <- err
+		}()
+		if i == 0 {
+			<-started
+		}
+	}
+	waitForDups(&g, "key", n-1)
+
+	cancel()
+	for i := 0; i < n; i++ {
+		if err := <-errs; err != context.Canceled {
+			t.Errorf("DoContext with a canceled ctx returned %v; want %v", err, context.Canceled)
+		}
+	}
+	select {
+	case <-fnCanceled:
+	case <-time.After(time.Second):
+		t.Fatal("fn's Context was not canceled after all callers gave up")
+	}
+
+	v, err, _ := g.DoContext(context.Background(), "key", fn)
+	if v != "fresh" || err != nil {
+		t.Errorf("DoContext after abandoned call = %v, %v; want %q, nil", v, err, "fresh")
+	}
+	if got := atomic.LoadInt32(&calls); got != 2 {
+		t.Errorf("fn called %d times; want 2", got)
+	}
+}
+
+func TestDoContextSurvivesPartialCancel(t *testing.T) {
+	var g Group
+	started := make(chan struct{})
+	unblock := make(chan struct{})
+	fn := func(ctx context.Context) (interface{}, error) {
+		close(started)
+		select {
+		case <-unblock:
+			return "bar", nil
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		}
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	errc := make(chan error, 1)
+	go func() {
+		_, err, _ := g.DoContext(ctx, "key", fn)
+		errc <- err
+	}()
+	<-started
+
+	res := make(chan interface{}, 1)
+	go func() {
+		v, _, _ := g.DoContext(context.Background(), "key", fn)
+		res <- v
+	}()
+	waitForDups(&g, "key", 1)
+
+	cancel()
+	if err := <-errc; err != context.Canceled {
+		t.Errorf("canceled caller got %v; want %v", err, context.Canceled)
+	}
+	close(unblock)
+	if v := <-res; v != "bar" {
+		t.Errorf("remaining caller got %v; want %q", v, "bar")
+	}
+}
+
+func TestDoChanContextSharedDeadline(t *testing.T) {
+	var g Group
+	fnCtx := make(chan context.Context, 1)
+	unblock := make(chan struct{})
+	fn := func(ctx context.Context) (interface{}, error) {
+		fnCtx <- ctx
+		<-unblock
+		return "bar", nil
+	}
+	waitForDeadline := func(ctx context.Context, want time.Time, wantOK bool) {
+		t.Helper()
+		for start := time.Now(); time.Since(start) < time.Second; time.Sleep(time.Millisecond) {
+			if d, ok := ctx.Deadline(); d.Equal(want) && ok == wantOK {
+				return
+			}
+		}
+		d, ok := ctx.Deadline()
+		t.Fatalf("shared Deadline() = %v, %t; want %v, %t", d, ok, want, wantOK)
+	}
+
+	now := time.Now()
+	short, long := now.Add(time.Hour), now.Add(2*time.Hour)
+	ctx1, cancel1 := context.WithDeadline(context.Background(), short)
+	defer cancel1()
+	ctx2, cancel2 := context.WithDeadline(context.Background(), long)
+	defer cancel2()
+
+	ch1 := g.DoChanContext(ctx1, "key", fn)
+	ctx := <-fnCtx
+	waitForDeadline(ctx, short, true)
+
+	ch2 := g.DoChanContext(ctx2, "key", fn)
+	waitForDeadline(ctx, long, true)
+
+	cancel2()
+	if res := <-ch2; res.Err != context.Canceled {
+		t.Fatalf("DoChanContext with a canceled ctx delivered %v; want %v", res.Err, context.Canceled)
+	}
+	waitForDeadline(ctx, short, true)
+
+	// A caller without a deadline lifts the deadline altogether.
+	ch3 := g.DoChan("key", func() (interface{}, error) {
+		t.Error("fn unexpectedly called")
+		return nil, nil
+	})
+	waitForDeadline(ctx, time.Time{}, false)
+
+	close(unblock)
+	for _, ch := range []<-chan Result{ch1, ch3} {
+		if res := <-ch; res.Val != "bar" || res.Err != nil || !res.Shared {
+			t.Errorf("DoChanContext delivered %v; want {bar <nil> true}", res)
+		}
+	}
+	if ctx.Err() == nil {
+		t.Error("shared Context not canceled after the call completed")
+	}
+}
+
+func TestDoChanContextDeadlineExceeded(t *testing.T) {
+	var g Group
+	ctx1, cancel1 := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel1()
+	ctx2, cancel2 := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel2()
+
+	fnErr := make(chan error, 1)
+	fn := func(ctx context.Context) (interface{}, error) {
+		<-ctx.Done()
+		fnErr <- ctx.Err()
+		return nil, ctx.Err()
+	}
+	ch1 := g.DoChanContext(ctx1, "key", fn)
+	ch2 := g.DoChanContext(ctx2, "key", fn)
+
+	// Each caller returns at its own deadline.
+	if res := <-ch1; res.Err != context.DeadlineExceeded {
+		t.Errorf("first caller got %v; want %v", res.Err, context.DeadlineExceeded)
+	}
+	select {
+	case err := <-fnErr:
+		t.Fatalf("fn's Context done with %v before the last caller's deadline", err)
+	default:
+	}
+	if res := <-ch2; res.Err != context.DeadlineExceeded {
+		t.Errorf("second caller got %v; want %v", res.Err, context.DeadlineExceeded)
+	}
+	if err := <-fnErr; err != context.DeadlineExceeded {
+		t.Errorf("fn's Context Err() = %v; want %v", err, context.DeadlineExceeded)
+	}
+}
+
+func TestDoChanContextSoleSubscriberCancels(t *testing.T) {
+	var g Group
+	ctx, cancel := context.WithCancel(context.Background())
+	started := make(chan struct{})
+	fnErr := make(chan error, 1)
+	ch := g.DoChanContext(ctx, "key", func(ctx context.Context) (interface{}, error) {
+		close(started)
+		<-ctx.Done()
+		fnErr <- ctx.Err()
+		return "stale", nil
+	})
+	<-started
+
+	cancel()
+	if res := <-ch; res.Err != context.Canceled {
+		t.Errorf("subscriber got %v; want %v", res.Err, context.Canceled)
+	}
+	select {
+	case err := <-fnErr:
+		if err != context.Canceled {
+			t.Errorf("fn's Context Err() = %v; want %v", err, context.Canceled)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("fn's Context was not canceled after its only subscriber gave up")
+	}
+
+	// The key was forgotten: the next call runs fn afresh.
+	if n := g.PendingCalls(); n != 0 {
+		t.Errorf("PendingCalls() = %d after the only subscriber gave up; want 0", n)
+	}
+	res := <-g.DoChanContext(context.Background(), "key", func(context.Context) (interface{}, error) {
+		return "fresh", nil
+	})
+	if res.Val != "fresh" || res.Shared {
+		t.Errorf("next DoChanContext = %+v; want a fresh, unshared result", res)
+	}
+}
@@ -0,0 +1,88 @@
+// Copyright 2026 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package singleflight
+
+import (
+	"runtime"
+	"sort"
+	"strconv"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+// hotKeyStorm starts workers goroutines calling Do and DoChan for a single
+// hot key until the returned function is called.
+func hotKeyStorm(g *Group, workers int) (stop func()) {
+	var done atomic.Bool
+	var wg sync.WaitGroup
+	fn := func() (interface{}, error) {
+		runtime.Gosched() // Let duplicates pile up.
+		return "hot", nil
+	}
+	for i := 0; i < workers; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for !done.Load() {
+				if i%2 == 0 {
+					g.Do("hot", fn)
+				} else {
+					<-g.DoChan("hot", fn)
+				}
+			}
+		}()
+	}
+	return func() {
+		done.Store(true)
+		wg.Wait()
+	}
+}
+
+// coldKeyLatencies returns the latencies of n calls to Do, each for a key of
+// its own, sorted in increasing order.
+func coldKeyLatencies(g *Group, n int) []time.Duration {
+	lat := make([]time.Duration, n)
+	for i := range lat {
+		key := "cold" + strconv.Itoa(i)
+		start := time.Now()
+		g.Do(key, func() (interface{}, error) { return key, nil })
+		lat[i] = time.Since(start)
+	}
+	sort.Slice(lat, func(i, j int) bool { return lat[i] < lat[j] })
+	return lat
+}
+
+// TestColdKeyLatency checks that calls for cold keys are not starved by a
+// storm of calls for a hot key sharing the same Group.
+func TestColdKeyLatency(t *testing.T) {
+	if testing.Short() {
+		t.Skip("skipping in short mode")
+	}
+	var g Group
+	stop := hotKeyStorm(&g, 4*runtime.GOMAXPROCS(0))
+	lat := coldKeyLatencies(&g, 1000)
+	stop()
+
+	// The bound is loose, to leave room for slow and heavily loaded
+	// machines; a starved caller would wait for as long as the storm lasts.
+	const bound = time.Second
+	if max := lat[len(lat)-1]; max > bound {
+		t.Errorf("slowest cold-key call took %v; want at most %v", max, bound)
+	}
+}
+
+func BenchmarkHotColdKeys(b *testing.B) {
+	var g Group
+	stop := hotKeyStorm(&g, 4*runtime.GOMAXPROCS(0))
+	defer stop()
+
+	b.ResetTimer()
+	lat := coldKeyLatencies(&g, b.N)
+	b.StopTimer()
+	b.ReportMetric(float64(lat[len(lat)*99/100].Nanoseconds()), "p99-ns/op")
+	b.ReportMetric(float64(lat[len(lat)-1].Nanoseconds()), "max-ns/op")
+}
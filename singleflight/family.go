@@ -0,0 +1,91 @@
+// Copyright 2026 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package singleflight
+
+// DoFamily is like Do, but also makes key a member of family, so that
+// ForgetFamily(family) forgets key along with the other members. A key
+// belongs to at most one family, the one given in the latest call to
+// DoFamily for it, for as long as g knows about key: while a call for it is
+// in flight, or its result is retained or within its grace period.
+func (g *Group) DoFamily(family, key string, fn func() (interface{}, error)) (v interface{}, err error, shared bool) {
+	g.mu.Lock()
+	if g.familyCalls == nil {
+		g.familyCalls = make(map[string]int)
+	}
+	g.familyCalls[key]++
+	if old, ok := g.family[key]; !ok || old != family {
+		g.leaveFamily(key)
+		if g.families == nil {
+			g.families = make(map[string]map[string]bool)
+			g.family = make(map[string]string)
+		}
+		keys := g.families[family]
+		if keys == nil {
+			keys = make(map[string]bool)
+			g.families[family] = keys
+		}
+		keys[key] = true
+		g.family[key] = family
+	}
+	g.mu.Unlock()
+
+	defer func() {
+		g.mu.Lock()
+		defer g.mu.Unlock()
+		if g.familyCalls[key]--; g.familyCalls[key] == 0 {
+			delete(g.familyCalls, key)
+		}
+		g.pruneFamily(key)
+	}()
+	return g.Do(key, fn)
+}
+
+// ForgetFamily forgets every key of family, as Forget would, and reports
+// how many of them g knew about.
+func (g *Group) ForgetFamily(family string) int {
+	g.mu.Lock()
+	defer g.unlock()
+	n := 0
+	for key := range g.families[family] {
+		if g.forget(key) {
+			n++
+		}
+	}
+	return n
+}
+
+// leaveFamily removes key from its family, if it has one.
+// g.mu must be held.
+func (g *Group) leaveFamily(key string) {
+	family, ok := g.family[key]
+	if !ok {
+		return
+	}
+	delete(g.family, key)
+	keys := g.families[family]
+	delete(keys, key)
+	if len(keys) == 0 {
+		delete(g.families, family)
+	}
+}
+
+// pruneFamily removes key from its family once g no longer knows about it,
+// so that keys that are done with do not accumulate in the index.
+// g.mu must be held.
+func (g *Group) pruneFamily(key string) {
+	if _, ok := g.family[key]; !ok || g.familyCalls[key] > 0 {
+		return
+	}
+	if _, ok := g.m[key]; ok {
+		return
+	}
+	if _, ok := g.cache[key]; ok {
+		return
+	}
+	if _, ok := g.recent[key]; ok {
+		return
+	}
+	g.leaveFamily(key)
+}
@@ -0,0 +1,73 @@
+// Copyright 2026 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package singleflight
+
+import "testing"
+
+func TestForgetFamily(t *testing.T) {
+	var g Group
+	g.SetMaxBytes(10)
+
+	calls := make(map[string]int)
+	do := func(family, key string) interface{} {
+		v, _, _ := g.DoFamily(family, key, func() (interface{}, error) {
+			calls[key]++
+			return calls[key], nil
+		})
+		return v
+	}
+	for _, key := range []string{"user:1:name", "user:1:email"} {
+		do("user:1", key)
+	}
+	do("user:2", "user:2:name")
+
+	if n := g.ForgetFamily("user:1"); n != 2 {
+		t.Errorf("ForgetFamily(user:1) = %d; want 2", n)
+	}
+	if v := do("user:1", "user:1:name"); v != 2 {
+		t.Errorf("Do for a forgotten family member = %v; want a fresh call", v)
+	}
+	if v := do("user:2", "user:2:name"); v != 1 {
+		t.Errorf("Do for a member of another family = %v; want the retained result", v)
+	}
+	if n := g.ForgetFamily("unknown"); n != 0 {
+		t.Errorf("ForgetFamily(unknown) = %d; want 0", n)
+	}
+}
+
+func TestDoFamilyPrunesIndex(t *testing.T) {
+	var g Group
+	indexed := func() (keys, families int) {
+		g.mu.Lock()
+		defer g.mu.Unlock()
+		return len(g.family), len(g.families)
+	}
+	ok := func() (interface{}, error) { return "ok", nil }
+
+	// Without memoization, a key leaves its family once its call returns.
+	for _, key := range []string{"a", "b", "c"} {
+		g.DoFamily("f", key, ok)
+	}
+	if keys, families := indexed(); keys != 0 || families != 0 {
+		t.Errorf("index has %d keys in %d families after the calls returned; want none", keys, families)
+	}
+
+	// A retained result keeps its key in the family until it is evicted.
+	g.SetMaxBytes(1)
+	g.DoFamily("f", "a", ok)
+	if keys, _ := indexed(); keys != 1 {
+		t.Errorf("index has %d keys with a retained result; want 1", keys)
+	}
+	g.DoFamily("f", "b", ok) // Evicts a.
+	if keys, _ := indexed(); keys != 1 {
+		t.Errorf("index has %d keys after an eviction; want 1", keys)
+	}
+	if n := g.ForgetFamily("f"); n != 1 {
+		t.Errorf("ForgetFamily(f) = %d; want 1", n)
+	}
+	if keys, families := indexed(); keys != 0 || families != 0 {
+		t.Errorf("index has %d keys in %d families after ForgetFamily; want none", keys, families)
+	}
+}
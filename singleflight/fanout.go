@@ -0,0 +1,37 @@
+// Copyright 2026 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package singleflight
+
+// DoFanout is like Do for a function that computes the results for several
+// keys at once. Duplicate calls are suppressed by primaryKey, and when fn
+// succeeds, each value in the map it returns is retained as the result for
+// its key, so that later calls for any of those keys return it without
+// calling their own fn. Calls for those keys already in flight are not
+// affected.
+//
+// The retained result for primaryKey itself is the whole map, as for Do.
+// DoFanout retains nothing unless memoization is enabled; see SetMaxBytes.
+func (g *Group) DoFanout(primaryKey string, fn func() (map[string]interface{}, error)) (m map[string]interface{}, err error, shared bool) {
+	v, err, shared := g.Do(primaryKey, func() (interface{}, error) {
+		m, err := fn()
+		if err != nil {
+			return nil, err
+		}
+		sizes := make(map[string]int64, len(m))
+		for key, v := range m {
+			if key != primaryKey {
+				sizes[key] = g.sizeOf(v)
+			}
+		}
+		g.mu.Lock()
+		for key, size := range sizes {
+			g.retain(key, m[key], nil, size)
+		}
+		g.unlock()
+		return m, nil
+	})
+	m, _ = v.(map[string]interface{})
+	return m, err, shared
+}
@@ -0,0 +1,37 @@
+// Copyright 2026 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package singleflight
+
+import "testing"
+
+func TestDoFanout(t *testing.T) {
+	var g Group
+	g.SetMaxBytes(10)
+
+	calls := 0
+	m, err, _ := g.DoFanout("users:1,2", func() (map[string]interface{}, error) {
+		calls++
+		return map[string]interface{}{"user:1": "alice", "user:2": "bob"}, nil
+	})
+	if err != nil || len(m) != 2 {
+		t.Fatalf("DoFanout = %v, %v; want both users", m, err)
+	}
+
+	fn := func() (interface{}, error) {
+		calls++
+		return "fetched", nil
+	}
+	for key, want := range map[string]string{"user:1": "alice", "user:2": "bob"} {
+		if v, _, shared := g.Do(key, fn); v != want || !shared {
+			t.Errorf("Do(%q) = %v, shared %t; want retained %q", key, v, shared, want)
+		}
+	}
+	if v, _, _ := g.Do("user:3", fn); v != "fetched" {
+		t.Errorf("Do for a key outside the fan-out = %v; want %q", v, "fetched")
+	}
+	if calls != 2 {
+		t.Errorf("functions called %d times; want 2", calls)
+	}
+}
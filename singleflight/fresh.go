@@ -0,0 +1,37 @@
+// Copyright 2026 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package singleflight
+
+import "time"
+
+// DoFresh is like Do, but joins a call for key already in flight only if
+// that call started no more than maxAge ago. Otherwise the call in flight is
+// presumed stuck and DoFresh supersedes it with a new call to fn: later
+// callers join the new call, while the callers already waiting for the old
+// one keep waiting for it, and its result is not retained.
+func (g *Group) DoFresh(key string, maxAge time.Duration, fn func() (interface{}, error)) (v interface{}, err error, shared bool) {
+	g.mu.Lock()
+	if g.m == nil {
+		g.m = make(map[string]*call)
+	}
+	if e, ok := g.lookup(key); ok {
+		g.unlock()
+		return e.val, e.err, true
+	}
+	if c, ok := g.m[key]; ok {
+		if g.now().Sub(c.start) <= maxAge {
+			return g.join(c, false)
+		}
+		// Supersede the stale call, as if key had been forgotten.
+		c.forgotten = true
+	}
+	c := new(call)
+	c.wg.Add(1)
+	g.register(key, c)
+	g.unlock()
+
+	g.doCall(c, key, fn)
+	return c.val, c.err, c.dups > 0
+}
@@ -0,0 +1,52 @@
+// Copyright 2026 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package singleflight
+
+import (
+	"runtime"
+	"testing"
+	"time"
+)
+
+func TestDoFresh(t *testing.T) {
+	var g Group
+	clock := newFakeClock()
+	g.setClock(clock.Now)
+
+	hung := make(chan struct{})
+	defer close(hung)
+	go g.Do("key", func() (interface{}, error) {
+		<-hung
+		return "stale", nil
+	})
+	for waitForDups(&g, "key", 0) == nil {
+		runtime.Gosched()
+	}
+
+	// A call that started recently enough is joined.
+	clock.Advance(time.Second)
+	joined := make(chan interface{}, 1)
+	go func() {
+		v, _, _ := g.DoFresh("key", time.Second, func() (interface{}, error) {
+			return "unexpected", nil
+		})
+		joined <- v
+	}()
+	waitForDups(&g, "key", 1)
+
+	// An older one is superseded.
+	clock.Advance(time.Nanosecond)
+	v, _, shared := g.DoFresh("key", time.Second, func() (interface{}, error) {
+		return "fresh", nil
+	})
+	if v != "fresh" || shared {
+		t.Errorf("DoFresh with a stale call in flight = %v, shared %t; want %q, false", v, shared, "fresh")
+	}
+	select {
+	case v := <-joined:
+		t.Fatalf("caller that joined the stale call returned %v before it completed", v)
+	default:
+	}
+}
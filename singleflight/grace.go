@@ -0,0 +1,70 @@
+// Copyright 2026 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package singleflight
+
+import "time"
+
+// SetGracePeriod extends the window in which calls for a key are
+// deduplicated past the completion of the call: for d after a call returns,
+// later calls for its key return its result, marked as shared, instead of
+// calling fn again, so that calls that are nearly but not strictly
+// concurrent are still deduplicated. Unlike memoization (see SetMaxBytes),
+// this applies to errors as well, but not to panics.
+//
+// A d <= 0, the default, disables the grace period.
+func (g *Group) SetGracePeriod(d time.Duration) {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	g.grace = d
+}
+
+// linger keeps the result of a call for key that just completed for the
+// grace period.
+// g.mu must be held.
+func (g *Group) linger(key string, val interface{}, err error) {
+	if g.grace <= 0 {
+		return
+	}
+	if g.recent == nil {
+		g.recent = make(map[string]*entry)
+	}
+	now := g.now()
+	g.recent[key] = &entry{key: key, val: val, err: err, expires: now.Add(g.grace)}
+	if len(g.recent) >= 2*g.recentSwept {
+		g.sweepRecent(now)
+	}
+}
+
+// sweepRecent discards the results whose grace period has ended, for keys
+// that have not been looked up since. Like sweepThrottled, it runs only once
+// the number of results has doubled since the last sweep.
+// g.mu must be held.
+func (g *Group) sweepRecent(now time.Time) {
+	for key, e := range g.recent {
+		if !now.Before(e.expires) {
+			delete(g.recent, key)
+			g.pruneFamily(key)
+		}
+	}
+	g.recentSwept = max(len(g.recent), 1)
+}
+
+// recentResult returns the result of a call for key that completed within
+// the grace period, if any, discarding it instead if the period has ended.
+// Results are discarded lazily, on lookup, so that the grace period follows
+// g's clock.
+// g.mu must be held.
+func (g *Group) recentResult(key string) (*entry, bool) {
+	e, ok := g.recent[key]
+	if !ok {
+		return nil, false
+	}
+	if !g.now().Before(e.expires) {
+		delete(g.recent, key)
+		g.pruneFamily(key)
+		return nil, false
+	}
+	return e, true
+}
@@ -0,0 +1,99 @@
+// Copyright 2026 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package singleflight
+
+import (
+	"errors"
+	"strconv"
+	"testing"
+	"time"
+)
+
+func TestSetGracePeriod(t *testing.T) {
+	var g Group
+	clock := newFakeClock()
+	g.setClock(clock.Now)
+	g.SetGracePeriod(time.Hour)
+
+	calls := 0
+	fn := func() (interface{}, error) {
+		calls++
+		return calls, nil
+	}
+	if v, _, shared := g.Do("key", fn); v != 1 || shared {
+		t.Fatalf("first Do = %v, shared %t; want 1, false", v, shared)
+	}
+	clock.Advance(time.Hour - 1)
+	if v, _, shared := g.Do("key", fn); v != 1 || !shared {
+		t.Errorf("Do within the grace period = %v, shared %t; want 1, true", v, shared)
+	}
+	if calls != 1 {
+		t.Errorf("fn called %d times within the grace period; want 1", calls)
+	}
+
+	clock.Advance(1)
+	if v, _, shared := g.Do("key", fn); v != 2 || shared {
+		t.Errorf("Do after the grace period = %v, shared %t; want 2, false", v, shared)
+	}
+}
+
+func TestSetGracePeriodFollowsClock(t *testing.T) {
+	var g Group
+	clock := newFakeClock()
+	g.setClock(clock.Now)
+	g.SetGracePeriod(time.Millisecond)
+
+	calls := 0
+	fn := func() (interface{}, error) {
+		calls++
+		return calls, nil
+	}
+	g.Do("key", fn)
+	time.Sleep(10 * time.Millisecond) // Real time passes, but not g's.
+	if v, _, shared := g.Do("key", fn); v != 1 || !shared {
+		t.Errorf("Do within the grace period by g's clock = %v, shared %t; want 1, true", v, shared)
+	}
+
+	// Results whose grace period has ended are swept even if their keys
+	// are never looked up again.
+	for i := 0; i < 100; i++ {
+		g.Do(strconv.Itoa(i), fn)
+	}
+	clock.Advance(time.Millisecond)
+	for i := 100; i < 200; i++ {
+		g.Do(strconv.Itoa(i), fn)
+	}
+	g.mu.Lock()
+	n := len(g.recent)
+	g.mu.Unlock()
+	if n > 100 {
+		t.Errorf("len(g.recent) = %d after the first results went stale; want at most 100", n)
+	}
+}
+
+func TestSetGracePeriodErrorAndForget(t *testing.T) {
+	var g Group
+	g.SetGracePeriod(time.Hour)
+
+	someErr := errors.New("some error")
+	calls := 0
+	fn := func() (interface{}, error) {
+		calls++
+		return nil, someErr
+	}
+	g.Do("key", fn)
+	if _, err, _ := g.Do("key", fn); err != someErr {
+		t.Errorf("Do within the grace period: error = %v; want %v", err, someErr)
+	}
+	if calls != 1 {
+		t.Errorf("fn called %d times within the grace period; want 1", calls)
+	}
+
+	g.Forget("key")
+	g.Do("key", fn)
+	if calls != 2 {
+		t.Errorf("fn called %d times after Forget; want 2", calls)
+	}
+}
@@ -0,0 +1,54 @@
+// Copyright 2026 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package singleflight
+
+import (
+	"context"
+
+	"golang.org/x/sync/semaphore"
+)
+
+// SetFamilyLimiter bounds the number of calls to fn that run at once for
+// keys sharing a backend: before calling fn for a key, g acquires a weight
+// of 1 from limits[family(key)], and releases it when fn returns, so that at
+// most the size of that semaphore distinct keys of the family are computed
+// concurrently. Keys whose family has no semaphore in limits are not
+// limited. Calls waiting for the semaphore count as in flight, so callers
+// for the same key still join them.
+//
+// If the semaphore cannot be acquired, because its size is 0 or because
+// the Context of a call made with DoContext or DoChanContext is done while
+// it waits, fn is not called and the call returns the error from Acquire. A
+// nil family removes the limits. The family function is called without g's
+// lock held, so it may call methods of g.
+func (g *Group) SetFamilyLimiter(family func(key string) string, limits map[string]*semaphore.Weighted) {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	g.limiterFamily = family
+	g.limiters = limits
+}
+
+// limit calls fn for key within the limit of its family, if any, waiting
+// for the limit until ctx is done. A nil ctx waits indefinitely.
+func (g *Group) limit(ctx context.Context, key string, fn func() (interface{}, error)) (interface{}, error) {
+	g.mu.Lock()
+	family, limits := g.limiterFamily, g.limiters
+	g.mu.Unlock()
+	if family == nil {
+		return fn()
+	}
+	sem := limits[family(key)]
+	if sem == nil {
+		return fn()
+	}
+	if ctx == nil {
+		ctx = context.Background()
+	}
+	if err := sem.Acquire(ctx, 1); err != nil {
+		return nil, err
+	}
+	defer sem.Release(1)
+	return fn()
+}
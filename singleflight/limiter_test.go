@@ -0,0 +1,112 @@
+// Copyright 2026 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package singleflight
+
+import (
+	"context"
+	"fmt"
+	"runtime"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"golang.org/x/sync/semaphore"
+)
+
+func TestSetFamilyLimiter(t *testing.T) {
+	var g Group
+	g.SetFamilyLimiter(func(key string) string {
+		family, _, _ := strings.Cut(key, ":")
+		return family
+	}, map[string]*semaphore.Weighted{"db": semaphore.NewWeighted(2)})
+
+	var running, peak atomic.Int32
+	unblock := make(chan struct{})
+	fn := func() (interface{}, error) {
+		n := running.Add(1)
+		for p := peak.Load(); n > p && !peak.CompareAndSwap(p, n); p = peak.Load() {
+		}
+		<-unblock
+		running.Add(-1)
+		return nil, nil
+	}
+	var chans []<-chan Result
+	for i := 0; i < 5; i++ {
+		chans = append(chans, g.DoChan(fmt.Sprint("db:", i), fn))
+	}
+
+	// A key of another family is not held back.
+	other := g.DoChan("cache:0", func() (interface{}, error) { return "cache", nil })
+	select {
+	case <-other:
+	case <-time.After(5 * time.Second):
+		t.Fatal("call for an unlimited family did not run")
+	}
+
+	for running.Load() != 2 {
+		runtime.Gosched()
+	}
+	time.Sleep(10 * time.Millisecond) // Give a third call a chance to start.
+	close(unblock)
+	for _, ch := range chans {
+		<-ch
+	}
+	if p := peak.Load(); p != 2 {
+		t.Errorf("at most %d calls of the limited family ran at once; want 2", p)
+	}
+}
+
+func TestFamilyLimiterContext(t *testing.T) {
+	var g Group
+	sem := semaphore.NewWeighted(1)
+	g.SetFamilyLimiter(func(key string) string {
+		g.PendingCalls() // The family function may call methods of g.
+		return "db"
+	}, map[string]*semaphore.Weighted{"db": sem})
+	sem.MustAcquire(context.Background(), 1) // Hold back the call.
+
+	ctx, cancel := context.WithCancel(context.Background())
+	ran := false
+	errc := make(chan error)
+	go func() {
+		_, err, _ := g.DoContext(ctx, "db:0", func(context.Context) (interface{}, error) {
+			ran = true
+			return nil, nil
+		})
+		errc <- err
+	}()
+	var c *call
+	for c == nil {
+		runtime.Gosched()
+		g.mu.Lock()
+		c = g.m["db:0"]
+		g.mu.Unlock()
+	}
+	cancel()
+	if err := <-errc; err != context.Canceled {
+		t.Fatalf("DoContext = %v; want %v", err, context.Canceled)
+	}
+
+	// Once its only caller has given up, the call stops waiting for the
+	// limiter without calling fn.
+	done := make(chan struct{})
+	go func() {
+		c.wg.Wait()
+		close(done)
+	}()
+	select {
+	case <-done:
+	case <-time.After(5 * time.Second):
+		t.Fatal("call kept waiting for the limiter after its caller gave up")
+	}
+	sem.Release(1)
+	if ran {
+		t.Error("fn ran after its caller gave up")
+	}
+	if c.err != context.Canceled {
+		t.Errorf("call returned %v; want %v", c.err, context.Canceled)
+	}
+}
@@ -0,0 +1,24 @@
+// Copyright 2026 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package singleflight
+
+// DoPrivate is like Do, except that the result of a call started by
+// DoPrivate is private to its caller: if a call for key is in flight,
+// DoPrivate waits for it and returns its result, as Do would, but otherwise
+// it calls fn without registering the call, so that no other caller can
+// join it and its result is never retained. Retained results are ignored.
+//
+// DoPrivate is meant for calls, such as debugging probes, whose results
+// must not be served to other callers.
+func (g *Group) DoPrivate(key string, fn func() (interface{}, error)) (v interface{}, err error, shared bool) {
+	g.mu.Lock()
+	c, ok := g.m[key]
+	if !ok {
+		g.mu.Unlock()
+		v, err = g.limit(nil, key, fn)
+		return v, err, false
+	}
+	return g.join(c, false)
+}
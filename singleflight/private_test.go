@@ -0,0 +1,54 @@
+// Copyright 2026 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package singleflight
+
+import (
+	"runtime"
+	"testing"
+)
+
+func TestDoPrivate(t *testing.T) {
+	var g Group
+	g.SetMaxBytes(10)
+
+	// A private call leaves nothing behind.
+	v, _, shared := g.DoPrivate("key", func() (interface{}, error) {
+		return "probe", nil
+	})
+	if v != "probe" || shared {
+		t.Errorf("DoPrivate = %v, shared %t; want %q, false", v, shared, "probe")
+	}
+	if len(g.Snapshot()) != 0 {
+		t.Errorf("DoPrivate retained a result: %v", g.Snapshot())
+	}
+	if v, _, _ := g.Do("key", func() (interface{}, error) { return "real", nil }); v != "real" {
+		t.Errorf("Do after DoPrivate = %v; want %q", v, "real")
+	}
+
+	// A private call joins a call in flight.
+	unblock := make(chan struct{})
+	done := make(chan struct{})
+	go func() {
+		g.Do("other", func() (interface{}, error) {
+			<-unblock
+			return "shared", nil
+		})
+		close(done)
+	}()
+	for waitForDups(&g, "other", 0) == nil {
+		runtime.Gosched()
+	}
+	result := make(chan interface{})
+	go func() {
+		v, _, _ := g.DoPrivate("other", func() (interface{}, error) { return "probe", nil })
+		result <- v
+	}()
+	waitForDups(&g, "other", 1)
+	close(unblock)
+	if v := <-result; v != "shared" {
+		t.Errorf("DoPrivate joining a call in flight = %v; want %q", v, "shared")
+	}
+	<-done
+}
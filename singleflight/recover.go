@@ -0,0 +1,40 @@
+// Copyright 2026 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package singleflight
+
+import "fmt"
+
+// SetRecover controls whether the group recovers panics in fn. By default a
+// panic in fn is re-raised in every caller waiting for the call, and crashes
+// the program if any of them called DoChan, since a panic cannot be
+// delivered on a channel. When enabled, the panic is instead returned to
+// every caller, including those that called DoChan, as a *PanicError, which
+// can be inspected with errors.As.
+//
+// A recovered panic is never retained, not even for the grace period set by
+// SetGracePeriod. A call to runtime.Goexit in fn is not affected.
+func (g *Group) SetRecover(enabled bool) {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	g.recover = enabled
+}
+
+// A PanicError is a value recovered from a panic in fn, along with the stack
+// trace of the panic; see SetRecover.
+type PanicError struct {
+	Value interface{}
+	Stack []byte
+}
+
+func (p *PanicError) Error() string {
+	return fmt.Sprintf("singleflight: panic: %v\n\n%s", p.Value, p.Stack)
+}
+
+// Unwrap returns the panic value if it is an error, so that errors.Is and
+// errors.As see through a panic with an error value.
+func (p *PanicError) Unwrap() error {
+	err, _ := p.Value.(error)
+	return err
+}
@@ -0,0 +1,52 @@
+// Copyright 2026 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package singleflight
+
+import (
+	"errors"
+	"strings"
+	"testing"
+)
+
+func TestSetRecoverDoChan(t *testing.T) {
+	var g Group
+	g.SetRecover(true)
+
+	errBoom := errors.New("boom")
+	unblock := make(chan struct{})
+	fn := func() (interface{}, error) {
+		<-unblock
+		panic(errBoom)
+	}
+	ch1 := g.DoChan("key", fn)
+	ch2 := g.DoChan("key", fn)
+	close(unblock)
+
+	for i, ch := range []<-chan Result{ch1, ch2} {
+		res := <-ch
+		var pe *PanicError
+		if !errors.As(res.Err, &pe) {
+			t.Fatalf("subscriber %d: Err = %v; want a *PanicError", i, res.Err)
+		}
+		if pe.Value != errBoom {
+			t.Errorf("subscriber %d: PanicError.Value = %v; want %v", i, pe.Value, errBoom)
+		}
+		if !strings.Contains(string(pe.Stack), "TestSetRecoverDoChan") {
+			t.Errorf("subscriber %d: PanicError.Stack does not mention the panicking function:\n%s", i, pe.Stack)
+		}
+		if !errors.Is(res.Err, errBoom) {
+			t.Errorf("subscriber %d: errors.Is(Err, errBoom) = false; want true", i)
+		}
+		if !res.Shared {
+			t.Errorf("subscriber %d: Shared = false; want true", i)
+		}
+	}
+
+	// The panic is not retained: the next call calls fn again.
+	v, err, _ := g.Do("key", func() (interface{}, error) { return 1, nil })
+	if v != 1 || err != nil {
+		t.Errorf("Do after a recovered panic = %v, %v; want 1, nil", v, err)
+	}
+}
@@ -0,0 +1,21 @@
+// Copyright 2026 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package singleflight
+
+// DoRetry is like Do, but the caller that calls fn calls it up to attempts
+// times in all, for as long as it returns an error for which retryable
+// reports true. Callers that join the call wait for the retries and share
+// the final result, rather than each retrying on their own. An attempts < 1
+// is treated as 1, and a nil retryable retries every error.
+func (g *Group) DoRetry(key string, attempts int, retryable func(error) bool, fn func() (interface{}, error)) (v interface{}, err error, shared bool) {
+	return g.Do(key, func() (v interface{}, err error) {
+		for i := 0; ; i++ {
+			v, err = fn()
+			if err == nil || i+1 >= attempts || retryable != nil && !retryable(err) {
+				return v, err
+			}
+		}
+	})
+}
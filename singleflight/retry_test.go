@@ -0,0 +1,68 @@
+// Copyright 2026 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package singleflight
+
+import (
+	"errors"
+	"sync"
+	"testing"
+)
+
+func TestDoRetry(t *testing.T) {
+	var g Group
+	errTransient := errors.New("transient")
+	retryable := func(err error) bool { return err == errTransient }
+
+	const n = 5
+	var calls int
+	unblock := make(chan struct{})
+	fn := func() (interface{}, error) {
+		calls++
+		if calls == 1 {
+			<-unblock // Let the other callers join.
+		}
+		if calls <= 2 {
+			return nil, errTransient
+		}
+		return "ok", nil
+	}
+
+	var wg sync.WaitGroup
+	for i := 0; i < n; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			v, err, shared := g.DoRetry("key", 3, retryable, fn)
+			if v != "ok" || err != nil || !shared {
+				t.Errorf("DoRetry = %v, %v, %t; want ok, nil, true", v, err, shared)
+			}
+		}()
+	}
+	waitForDups(&g, "key", n-1)
+	close(unblock)
+	wg.Wait()
+	if calls != 3 {
+		t.Errorf("fn called %d times; want 3", calls)
+	}
+
+	// Errors that are not retryable, and the last attempt, end the call.
+	errFatal := errors.New("fatal")
+	calls = 0
+	_, err, _ := g.DoRetry("key", 3, retryable, func() (interface{}, error) {
+		calls++
+		return nil, errFatal
+	})
+	if err != errFatal || calls != 1 {
+		t.Errorf("DoRetry with a fatal error = %v after %d calls; want %v after 1", err, calls, errFatal)
+	}
+	calls = 0
+	_, err, _ = g.DoRetry("key", 2, nil, func() (interface{}, error) {
+		calls++
+		return nil, errTransient
+	})
+	if err != errTransient || calls != 2 {
+		t.Errorf("DoRetry failing every attempt = %v after %d calls; want %v after 2", err, calls, errTransient)
+	}
+}
@@ -0,0 +1,42 @@
+// Copyright 2026 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package singleflight
+
+import "strconv"
+
+// A Role tells a caller of DoAttributed whether it computed its result.
+type Role int
+
+const (
+	// Leader is the role of the caller whose fn produced the result.
+	Leader Role = iota
+	// Follower is the role of a caller that received the result of
+	// another caller's fn, by joining its call or from a retained result.
+	Follower
+)
+
+func (r Role) String() string {
+	switch r {
+	case Leader:
+		return "leader"
+	case Follower:
+		return "follower"
+	}
+	return "Role(" + strconv.Itoa(int(r)) + ")"
+}
+
+// DoAttributed is like Do, but instead of reporting whether the result was
+// shared, it reports the caller's role in producing it. Unlike shared, which
+// the caller that calls fn also sees as true once other callers join it,
+// the role tells that caller apart from those that received its result.
+func (g *Group) DoAttributed(key string, fn func() (interface{}, error)) (v interface{}, err error, role Role) {
+	role = Follower
+	v, err, _ = g.Do(key, func() (interface{}, error) {
+		// Do calls fn on the calling goroutine.
+		role = Leader
+		return fn()
+	})
+	return v, err, role
+}
@@ -0,0 +1,50 @@
+// Copyright 2026 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package singleflight
+
+import (
+	"sync"
+	"testing"
+)
+
+func TestDoAttributed(t *testing.T) {
+	var g Group
+	const n = 4
+	unblock := make(chan struct{})
+	fn := func() (interface{}, error) {
+		<-unblock
+		return "bar", nil
+	}
+
+	roles := make(chan Role, n)
+	var wg sync.WaitGroup
+	for i := 0; i < n; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			_, _, role := g.DoAttributed("key", fn)
+			roles <- role
+		}()
+	}
+	waitForDups(&g, "key", n-1)
+	close(unblock)
+	wg.Wait()
+	close(roles)
+
+	counts := make(map[Role]int)
+	for r := range roles {
+		counts[r]++
+	}
+	if counts[Leader] != 1 || counts[Follower] != n-1 {
+		t.Errorf("roles = %v; want 1 leader and %d followers", counts, n-1)
+	}
+
+	// A retained result is attributed to another caller.
+	g.SetMaxBytes(10)
+	g.DoAttributed("retained", func() (interface{}, error) { return 1, nil })
+	if _, _, role := g.DoAttributed("retained", fn); role != Follower {
+		t.Errorf("role for a retained result = %v; want %v", role, Follower)
+	}
+}
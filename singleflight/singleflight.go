@@ -8,11 +8,16 @@ package singleflight // import "golang.org/x/sync/singleflight"
 
 import (
 	"bytes"
+	"container/list"
+	"context"
 	"errors"
 	"fmt"
 	"runtime"
 	"runtime/debug"
 	"sync"
+	"time"
+
+	"golang.org/x/sync/semaphore"
 )
 
 // errGoexit indicates the runtime.Goexit was called in
@@ -31,18 +36,29 @@ func (p *panicError) Error() string {
 	return fmt.Sprintf("%v\n\n%s", p.value, p.stack)
 }
 
-func newPanicError(v interface{}) error {
+func newPanicError(v interface{}, trim bool) error {
 	stack := debug.Stack()
 
 	// The first line of the stack trace is of the form "goroutine N [status]:"
 	// but by the time the panic reaches Do the goroutine may no longer exist
 	// and its status will have changed. Trim out the misleading line.
-	if line := bytes.IndexByte(stack[:], '\n'); line >= 0 {
+	if line := bytes.IndexByte(stack[:], '\n'); line >= 0 && trim {
 		stack = stack[line+1:]
 	}
 	return &panicError{value: v, stack: stack}
 }
 
+// SetPanicStackTrim controls whether the stack trace recorded when fn
+// panics omits its first line, the "goroutine N [status]:" header, which is
+// the default. The header is trimmed because the status it reports is
+// usually stale by the time the panic is re-raised in a caller, but it does
+// identify the goroutine that ran fn.
+func (g *Group) SetPanicStackTrim(enabled bool) {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	g.keepStackHeader = !enabled
+}
+
 // call is an in-flight or completed singleflight.Do call
 type call struct {
 	wg sync.WaitGroup
@@ -59,15 +75,169 @@ type call struct {
 	// These fields are read and written with the singleflight
 	// mutex held before the WaitGroup is done, and are read but
 	// not written after the WaitGroup is done.
-	dups  int
-	chans []chan<- Result
+	dups    int
+	chans   []chan<- Result
+	waiters []chan struct{} // closed in order to release DoFIFO callers
+
+	// done, if non-nil, is closed with the singleflight mutex held once
+	// the call has completed. It is created on demand by callers that need
+	// to wait for the call in a select.
+	done chan struct{}
+
+	// detached reports whether fn runs on a goroutine of its own on behalf
+	// of DoContext callers, which re-raise any panic from fn themselves.
+	// refs counts the callers still waiting on a detached call; callers
+	// other than DoContext never stop waiting. When the last DoContext
+	// caller gives up, cancel cancels the Context passed to fn.
+	detached bool
+	refs     int
+	cancel   context.CancelFunc
+
+	// shared, if non-nil, is the Context passed to fn by DoChanContext.
+	// Its deadline is the latest deadline of the callers still waiting.
+	shared *sharedContext
+	subs   []*subscriber // DoChanContext callers
+
+	// ctx, if non-nil, is the Context passed to fn by DoContext or
+	// DoChanContext, which also bounds the wait in the family limiter.
+	ctx context.Context
+
+	start time.Time // when the call was registered; see DoFresh
 }
 
 // Group represents a class of work and forms a namespace in
 // which units of work can be executed with duplicate suppression.
 type Group struct {
-	mu sync.Mutex       // protects m
+	mu sync.Mutex       // protects m and the fields below
 	m  map[string]*call // lazily initialized
+
+	bounds []time.Duration // see SetDurationBuckets
+	counts []int64         // len(bounds)+1 counts of fn durations
+
+	cache    map[string]*entry // retained results; lazily initialized
+	lru      list.List         // of *entry, most recently used first
+	bytes    int64             // total size of the retained results
+	maxBytes int64             // see SetMaxBytes
+	sizeFunc func(interface{}) int64
+	onEvict  func(key string, v interface{}) // see SetOnEvict
+	evicted  []*entry                        // discarded since g.mu was locked, for onEvict
+	jitter   float64                         // see SetTTLJitter
+
+	limiterFamily func(key string) string        // see SetFamilyLimiter
+	limiters      map[string]*semaphore.Weighted // see SetFamilyLimiter
+
+	throttled      map[string]*throttled // see DoThrottled; lazily initialized
+	throttledSwept int                   // len(throttled) after the last sweep
+	breakers       map[string]*breaker   // see DoBreaker; lazily initialized
+	maxStream      int64                 // see SetMaxStreamBytes
+
+	keepStackHeader bool // see SetPanicStackTrim
+	recover         bool // see SetRecover
+
+	grace       time.Duration     // see SetGracePeriod
+	recent      map[string]*entry // results within their grace period
+	recentSwept int               // len(recent) after the last sweep
+
+	families    map[string]map[string]bool // family to its keys; see DoFamily
+	family      map[string]string          // key to its family
+	familyCalls map[string]int             // DoFamily calls in progress per key
+
+	clock func() time.Time // if non-nil, replaces time.Now; see setClock
+}
+
+// Stats holds statistics about the calls made by a Group.
+type Stats struct {
+	// DurationBuckets counts executions of fn by how long they ran, using
+	// the bounds given to SetDurationBuckets. It is empty if no buckets
+	// have been set.
+	DurationBuckets []DurationBucket
+}
+
+// A DurationBucket counts the executions of fn that ran for at most
+// UpperBound and longer than the UpperBound of the preceding bucket.
+// The UpperBound of the last bucket is the maximum time.Duration.
+type DurationBucket struct {
+	UpperBound time.Duration
+	Count      int64
+}
+
+const maxDuration = time.Duration(1<<63 - 1)
+
+// SetDurationBuckets sets the upper bounds of the buckets in which Stats
+// counts executions of fn by duration, and resets the counts. The bounds
+// must be in increasing order; executions longer than the last bound are
+// counted in a final, unbounded bucket. A nil bounds disables counting.
+func (g *Group) SetDurationBuckets(bounds []time.Duration) {
+	for i := 1; i < len(bounds); i++ {
+		if bounds[i] <= bounds[i-1] {
+			panic("singleflight: duration bucket bounds not in increasing order")
+		}
+	}
+
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	if len(bounds) == 0 {
+		g.bounds, g.counts = nil, nil
+		return
+	}
+	g.bounds = append([]time.Duration(nil), bounds...)
+	g.counts = make([]int64, len(bounds)+1)
+}
+
+// Stats returns a snapshot of the statistics collected by g.
+func (g *Group) Stats() Stats {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	var st Stats
+	if g.counts != nil {
+		st.DurationBuckets = make([]DurationBucket, len(g.counts))
+		for i, n := range g.counts {
+			b := maxDuration
+			if i < len(g.bounds) {
+				b = g.bounds[i]
+			}
+			st.DurationBuckets[i] = DurationBucket{UpperBound: b, Count: n}
+		}
+	}
+	return st
+}
+
+// observe records that an execution of fn took d.
+// g.mu must be held.
+func (g *Group) observe(d time.Duration) {
+	if g.counts == nil {
+		return
+	}
+	i := 0
+	for i < len(g.bounds) && d > g.bounds[i] {
+		i++
+	}
+	g.counts[i]++
+}
+
+// DefaultGroup is the Group used by the package-level Do, DoChan, and
+// Forget functions.
+//
+// All users of DefaultGroup in a program share a single key namespace, so
+// unrelated packages calling Do with the same key suppress each other's
+// calls. Packages should use keys that cannot collide with those of other
+// packages, such as keys prefixed with the package path, or use a Group of
+// their own.
+var DefaultGroup = new(Group)
+
+// Do calls DefaultGroup.Do.
+func Do(key string, fn func() (interface{}, error)) (v interface{}, err error, shared bool) {
+	return DefaultGroup.Do(key, fn)
+}
+
+// DoChan calls DefaultGroup.DoChan.
+func DoChan(key string, fn func() (interface{}, error)) <-chan Result {
+	return DefaultGroup.DoChan(key, fn)
+}
+
+// Forget calls DefaultGroup.Forget.
+func Forget(key string) {
+	DefaultGroup.Forget(key)
 }
 
 // Result holds the results of Do, so they can be passed
@@ -84,26 +254,55 @@ type Result struct {
 // original to complete and receives the same results.
 // The return value shared indicates whether v was given to multiple callers.
 func (g *Group) Do(key string, fn func() (interface{}, error)) (v interface{}, err error, shared bool) {
+	return g.do(key, fn, nil)
+}
+
+// do implements Do. If bind is non-nil and a new call c is started, c runs
+// bind(c) in place of fn, so that the function can refer to its own call.
+func (g *Group) do(key string, fn func() (interface{}, error), bind func(c *call) func() (interface{}, error)) (v interface{}, err error, shared bool) {
 	g.mu.Lock()
 	if g.m == nil {
 		g.m = make(map[string]*call)
 	}
+	if e, ok := g.lookup(key); ok {
+		g.unlock()
+		return e.val, e.err, true
+	}
 	if c, ok := g.m[key]; ok {
-		c.dups++
-		g.mu.Unlock()
-		c.wg.Wait()
+		return g.join(c, false)
+	}
+	c := new(call)
+	c.wg.Add(1)
+	g.register(key, c)
+	g.unlock()
 
-		if e, ok := c.err.(*panicError); ok {
-			panic(e)
-		} else if c.err == errGoexit {
-			runtime.Goexit()
-		}
-		return c.val, c.err, true
+	if bind != nil {
+		fn = bind(c)
+	}
+	g.doCall(c, key, fn)
+	return c.val, c.err, c.dups > 0
+}
+
+// DoFIFO is like Do, but once the original call completes, duplicate callers
+// are released in the order in which they called DoFIFO, rather than all at
+// once. Releasing a caller only makes its goroutine runnable, so the
+// scheduler may still run the released callers in a different order.
+func (g *Group) DoFIFO(key string, fn func() (interface{}, error)) (v interface{}, err error, shared bool) {
+	g.mu.Lock()
+	if g.m == nil {
+		g.m = make(map[string]*call)
+	}
+	if e, ok := g.lookup(key); ok {
+		g.unlock()
+		return e.val, e.err, true
+	}
+	if c, ok := g.m[key]; ok {
+		return g.join(c, true)
 	}
 	c := new(call)
 	c.wg.Add(1)
-	g.m[key] = c
-	g.mu.Unlock()
+	g.register(key, c)
+	g.unlock()
 
 	g.doCall(c, key, fn)
 	return c.val, c.err, c.dups > 0
@@ -112,6 +311,15 @@ func (g *Group) Do(key string, fn func() (interface{}, error)) (v interface{}, e
 // DoChan is like Do but returns a channel that will receive the
 // results when they are ready.
 //
+// Results are delivered to the channels of duplicate callers in the order in
+// which DoChan was called. If fn calls runtime.Goexit, the channels receive
+// a Result with a non-nil Err.
+//
+// Each channel is buffered and receives exactly one Result, so delivery
+// never blocks: a caller that is slow to receive, or never receives, holds
+// up neither the other callers nor the goroutine running fn, and an unread
+// channel is garbage collected like any other value.
+//
 // The returned channel will not be closed.
 func (g *Group) DoChan(key string, fn func() (interface{}, error)) <-chan Result {
 	ch := make(chan Result, 1)
@@ -119,26 +327,75 @@ func (g *Group) DoChan(key string, fn func() (interface{}, error)) <-chan Result
 	if g.m == nil {
 		g.m = make(map[string]*call)
 	}
+	if e, ok := g.lookup(key); ok {
+		g.unlock()
+		ch <- Result{e.val, e.err, true}
+		return ch
+	}
 	if c, ok := g.m[key]; ok {
 		c.dups++
+		c.refs++
+		c.shared.add(time.Time{}, false)
 		c.chans = append(c.chans, ch)
-		g.mu.Unlock()
+		g.unlock()
 		return ch
 	}
 	c := &call{chans: []chan<- Result{ch}}
 	c.wg.Add(1)
-	g.m[key] = c
-	g.mu.Unlock()
+	g.register(key, c)
+	g.unlock()
 
 	go g.doCall(c, key, fn)
 
 	return ch
 }
 
+// join makes the caller a duplicate of the in-flight call c and waits for c
+// to complete, released in arrival order if fifo is set (see DoFIFO), then
+// returns its result.
+// g.mu must be held, and is released with g.unlock.
+func (g *Group) join(c *call, fifo bool) (v interface{}, err error, shared bool) {
+	c.dups++
+	c.refs++
+	c.shared.add(time.Time{}, false)
+	var ready chan struct{}
+	if fifo {
+		ready = make(chan struct{})
+		c.waiters = append(c.waiters, ready)
+	}
+	g.unlock()
+	if fifo {
+		<-ready
+	} else {
+		c.wg.Wait()
+	}
+	v, err = c.result()
+	return v, err, true
+}
+
+// result returns the result of the completed call c to a caller, re-raising
+// in the caller a panic or runtime.Goexit from fn.
+func (c *call) result() (interface{}, error) {
+	if e, ok := c.err.(*panicError); ok {
+		panic(e)
+	} else if c.err == errGoexit {
+		runtime.Goexit()
+	}
+	return c.val, c.err
+}
+
+// register makes c the in-flight call for key.
+// g.mu must be held.
+func (g *Group) register(key string, c *call) {
+	c.start = g.now()
+	g.m[key] = c
+}
+
 // doCall handles the single call for a key.
 func (g *Group) doCall(c *call, key string, fn func() (interface{}, error)) {
 	normalReturn := false
 	recovered := false
+	start := g.now()
 
 	// use double-defer to distinguish panic from runtime.Goexit,
 	// more details see https://golang.org/cl/134395
@@ -149,28 +406,67 @@ func (g *Group) doCall(c *call, key string, fn func() (interface{}, error)) {
 		}
 
 		c.wg.Done()
+		elapsed := g.now().Sub(start)
+		// Measure the result before locking g.mu, as the size function
+		// may call methods of g.
+		size := int64(-1)
+		if normalReturn && c.err == nil {
+			size = g.sizeOf(c.val)
+		}
 		g.mu.Lock()
-		defer g.mu.Unlock()
-		if !c.forgotten {
+		defer g.unlock()
+		g.observe(elapsed)
+		// Forget marks c as forgotten with g.mu held, so it either happened
+		// before this point, in which case c no longer speaks for key and its
+		// result must not be retained, or it will happen after, in which case
+		// it discards whatever is retained here. Either way, the next call
+		// for key calls fn.
+		if !c.forgotten && g.m[key] == c {
 			delete(g.m, key)
+			if normalReturn && c.err == nil {
+				g.retain(key, c.val, c.err, size)
+			}
+			if normalReturn {
+				g.linger(key, c.val, c.err)
+			}
+			g.pruneFamily(key)
+		}
+		// No more callers can join c, so c.waiters is complete.
+		for _, ready := range c.waiters {
+			close(ready)
+		}
+		if c.done != nil {
+			close(c.done)
+		}
+		if c.cancel != nil {
+			c.cancel()
 		}
 
 		if e, ok := c.err.(*panicError); ok {
 			// In order to prevent the waiting channels from being blocked forever,
 			// needs to ensure that this panic cannot be recovered.
-			if len(c.chans) > 0 {
+			if len(c.chans) > 0 || len(c.subs) > 0 {
 				go panic(e)
 				select {} // Keep this goroutine around so that it will appear in the crash dump.
-			} else {
+			} else if !c.detached || c.refs == 0 {
 				panic(e)
 			}
-		} else if c.err == errGoexit {
-			// Already in the process of goexit, no need to call again
+			// Otherwise the callers waiting on the detached call re-raise
+			// the panic.
 		} else {
-			// Normal return
+			// Normal return, or fn called runtime.Goexit; we are already in
+			// the process of goexit, so there is no need to call it again, but
+			// DoChan callers must still hear about it rather than block forever.
 			for _, ch := range c.chans {
 				ch <- Result{c.val, c.err, c.dups > 0}
 			}
+			for _, sub := range c.subs {
+				if !sub.left {
+					sub.left = true
+					sub.stop()
+					sub.ch <- Result{c.val, c.err, c.dups > 0}
+				}
+			}
 		}
 	}()
 
@@ -185,12 +481,19 @@ func (g *Group) doCall(c *call, key string, fn func() (interface{}, error)) {
 				// the time we know that, the part of the stack trace relevant to the
 				// panic has been discarded.
 				if r := recover(); r != nil {
-					c.err = newPanicError(r)
+					g.mu.Lock()
+					trim, recoverPanics := !g.keepStackHeader, g.recover
+					g.mu.Unlock()
+					c.err = newPanicError(r, trim)
+					if recoverPanics {
+						// Return the panic as an ordinary error.
+						c.err = &PanicError{Value: r, Stack: c.err.(*panicError).stack}
+					}
 				}
 			}
 		}()
 
-		c.val, c.err = fn()
+		c.val, c.err = g.limit(c.ctx, key, fn)
 		normalReturn = true
 	}()
 
@@ -199,14 +502,67 @@ func (g *Group) doCall(c *call, key string, fn func() (interface{}, error)) {
 	}
 }
 
+// Done returns a channel that is closed when the call for key that is in
+// flight at the time of the call to Done completes, letting a caller observe
+// its completion without calling fn. If no call for key is in flight, the
+// channel returned is already closed.
+func (g *Group) Done(key string) <-chan struct{} {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	c, ok := g.m[key]
+	if !ok {
+		done := make(chan struct{})
+		close(done)
+		return done
+	}
+	if c.done == nil {
+		c.done = make(chan struct{})
+	}
+	return c.done
+}
+
+// PendingCalls returns the number of distinct keys for which a call is in
+// flight. Each such call started by DoChan or DoChanContext occupies a
+// goroutine of its own, so a steadily growing count can reveal a leak.
+// Results retained by memoization are not counted, nor are calls still in
+// flight for keys that have been forgotten.
+func (g *Group) PendingCalls() int {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	return len(g.m)
+}
+
 // Forget tells the singleflight to forget about a key.  Future calls
 // to Do for this key will call the function rather than waiting for
-// an earlier call to complete.
+// an earlier call to complete or returning its retained result.
+//
+// If a call for key is in flight, the callers already waiting for it still
+// receive its result, but the result is never retained, however close to the
+// completion of the call Forget is called.
 func (g *Group) Forget(key string) {
+	g.ForgetReport(key)
+}
+
+// ForgetReport is like Forget but reports whether g knew about key, that is,
+// whether a call for key was in flight or its result was retained.
+func (g *Group) ForgetReport(key string) bool {
 	g.mu.Lock()
-	if c, ok := g.m[key]; ok {
+	defer g.unlock()
+	return g.forget(key)
+}
+
+// forget implements ForgetReport.
+// g.mu must be held, and released with g.unlock.
+func (g *Group) forget(key string) bool {
+	c, ok := g.m[key]
+	if ok {
 		c.forgotten = true
 	}
 	delete(g.m, key)
-	g.mu.Unlock()
+	delete(g.throttled, key)
+	delete(g.breakers, key)
+	delete(g.recent, key)
+	g.leaveFamily(key)
+	retained := g.discard(key)
+	return ok || retained
 }
@@ -318,3 +318,385 @@ func TestPanicDoSharedByDoChan(t *testing.T) {
 		t.Errorf("Test subprocess failed, but the crash isn't caused by panicking in Do")
 	}
 }
+
+// waitForDups blocks until n duplicate callers are waiting on the in-flight
+// call for key.
+func waitForDups(g *Group, key string, n int) *call {
+	for {
+		g.mu.Lock()
+		c := g.m[key]
+		dups := 0
+		if c != nil {
+			dups = c.dups
+		}
+		g.mu.Unlock()
+		if dups == n {
+			return c
+		}
+		runtime.Gosched()
+	}
+}
+
+// checkReleasedInOrder fails t unless ready(i) becoming true for any i implies
+// ready(j) for all j < i, and returns once every ready(i) is true.
+func checkReleasedInOrder(t *testing.T, n int, ready func(i int) bool) {
+	t.Helper()
+	for {
+		// Poll from the back: if i is observed as ready, every j < i must
+		// be ready by the time it is polled.
+		all := true
+		sawReady := -1
+		for i := n - 1; i >= 0; i-- {
+			if ready(i) {
+				if sawReady < 0 {
+					sawReady = i
+				}
+			} else {
+				all = false
+				if sawReady > i {
+					t.Fatalf("waiter %d released before waiter %d", sawReady, i)
+				}
+			}
+		}
+		if all {
+			return
+		}
+		runtime.Gosched()
+	}
+}
+
+func TestPanicStackTrim(t *testing.T) {
+	stack := func(g *Group) string {
+		var r interface{}
+		func() {
+			defer func() { r = recover() }()
+			g.Do("key", func() (interface{}, error) {
+				panic("boom")
+			})
+		}()
+		return string(r.(*panicError).stack)
+	}
+
+	var g Group
+	if s := stack(&g); strings.HasPrefix(s, "goroutine ") {
+		t.Errorf("default stack starts with the goroutine header:\n%s", s)
+	}
+	g.SetPanicStackTrim(false)
+	if s := stack(&g); !strings.HasPrefix(s, "goroutine ") {
+		t.Errorf("untrimmed stack lacks the goroutine header:\n%s", s)
+	}
+}
+
+func TestDoChanDeliveryOrder(t *testing.T) {
+	var g Group
+	unblock := make(chan struct{})
+	fn := func() (interface{}, error) {
+		<-unblock
+		return "bar", nil
+	}
+
+	const n = 10
+	chans := make([]<-chan Result, n)
+	for i := range chans {
+		chans[i] = g.DoChan("key", fn)
+	}
+	close(unblock)
+
+	checkReleasedInOrder(t, n, func(i int) bool { return len(chans[i]) > 0 })
+	for i, ch := range chans {
+		if r := <-ch; r.Val != "bar" || !r.Shared {
+			t.Errorf("chans[%d] received %+v; want shared %q", i, r, "bar")
+		}
+	}
+}
+
+func TestDoChanUnreadSubscriber(t *testing.T) {
+	var g Group
+	unblock := make(chan struct{})
+	returned := make(chan struct{})
+	fn := func() (interface{}, error) {
+		<-unblock
+		return "bar", nil
+	}
+
+	_ = g.DoChan("key", fn) // Never read.
+	ch := g.DoChan("key", fn)
+	g.DoChan("key", fn) // Never read, and not even kept.
+	waitForDups(&g, "key", 2)
+	close(unblock)
+
+	select {
+	case r := <-ch:
+		if r.Val != "bar" {
+			t.Errorf("DoChan received %+v; want %q", r, "bar")
+		}
+	case <-time.After(time.Second):
+		t.Fatal("subscriber starved by subscribers that never read")
+	}
+	go func() {
+		// Results are delivered with g's lock held, so this call cannot
+		// proceed if delivery is stuck.
+		g.Do("other", func() (interface{}, error) { return nil, nil })
+		close(returned)
+	}()
+	select {
+	case <-returned:
+	case <-time.After(time.Second):
+		t.Fatal("delivery held up by subscribers that never read")
+	}
+}
+
+func TestDoFIFO(t *testing.T) {
+	var g Group
+	started := make(chan struct{})
+	unblock := make(chan struct{})
+	go g.DoFIFO("key", func() (interface{}, error) {
+		close(started)
+		<-unblock
+		return "bar", nil
+	})
+	<-started
+
+	const n = 10
+	var wg sync.WaitGroup
+	for i := 0; i < n; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			v, err, shared := g.DoFIFO("key", func() (interface{}, error) {
+				t.Error("DoFIFO unexpectedly executed callback")
+				return nil, nil
+			})
+			if v != "bar" || err != nil || !shared {
+				t.Errorf("DoFIFO = %v, %v, %t; want %q, nil, true", v, err, shared, "bar")
+			}
+		}()
+		waitForDups(&g, "key", i+1)
+	}
+
+	c := waitForDups(&g, "key", n)
+	g.mu.Lock()
+	waiters := append([]chan struct{}(nil), c.waiters...)
+	g.mu.Unlock()
+	close(unblock)
+
+	// Only the order in which the callers are released is guaranteed, not
+	// the order in which they then run.
+	checkReleasedInOrder(t, n, func(i int) bool {
+		select {
+		case <-waiters[i]:
+			return true
+		default:
+			return false
+		}
+	})
+	wg.Wait()
+}
+
+func TestDurationBuckets(t *testing.T) {
+	var g Group
+	if st := g.Stats(); st.DurationBuckets != nil {
+		t.Errorf("Stats().DurationBuckets = %v before SetDurationBuckets; want nil", st.DurationBuckets)
+	}
+
+	g.SetDurationBuckets([]time.Duration{10 * time.Millisecond, 100 * time.Millisecond})
+	for i, d := range []time.Duration{0, 0, 20 * time.Millisecond, 150 * time.Millisecond} {
+		g.Do(fmt.Sprint(i), func() (interface{}, error) {
+			time.Sleep(d)
+			return nil, nil
+		})
+	}
+
+	want := []DurationBucket{
+		{UpperBound: 10 * time.Millisecond, Count: 2},
+		{UpperBound: 100 * time.Millisecond, Count: 1},
+		{UpperBound: maxDuration, Count: 1},
+	}
+	got := g.Stats().DurationBuckets
+	if fmt.Sprint(got) != fmt.Sprint(want) {
+		t.Errorf("Stats().DurationBuckets = %v; want %v", got, want)
+	}
+}
+
+func TestForgetReport(t *testing.T) {
+	var g Group
+	if g.ForgetReport("key") {
+		t.Error("ForgetReport of an unknown key = true; want false")
+	}
+
+	started := make(chan struct{})
+	unblock := make(chan struct{})
+	ch := g.DoChan("key", func() (interface{}, error) {
+		close(started)
+		<-unblock
+		return nil, nil
+	})
+	<-started
+	if !g.ForgetReport("key") {
+		t.Error("ForgetReport of an in-flight key = false; want true")
+	}
+	if g.ForgetReport("key") {
+		t.Error("ForgetReport of a forgotten key = true; want false")
+	}
+	close(unblock)
+	<-ch
+}
+
+func TestDone(t *testing.T) {
+	var g Group
+	select {
+	case <-g.Done("key"):
+	default:
+		t.Error("Done of a key not in flight is not closed")
+	}
+
+	started := make(chan struct{})
+	unblock := make(chan struct{})
+	ch := g.DoChan("key", func() (interface{}, error) {
+		close(started)
+		<-unblock
+		return nil, nil
+	})
+	<-started
+	done := g.Done("key")
+	select {
+	case <-done:
+		t.Fatal("Done closed while the call is in flight")
+	case <-time.After(10 * time.Millisecond):
+	}
+	close(unblock)
+	select {
+	case <-done:
+	case <-time.After(5 * time.Second):
+		t.Fatal("Done not closed after the call completed")
+	}
+	<-ch
+}
+
+func TestPendingCalls(t *testing.T) {
+	var g Group
+	g.SetMaxBytes(10)
+	g.Do("retained", func() (interface{}, error) { return 0, nil })
+
+	const n = 3
+	var started sync.WaitGroup
+	started.Add(n)
+	unblock := make(chan struct{})
+	var chans []<-chan Result
+	for i := 0; i < n; i++ {
+		chans = append(chans, g.DoChan(fmt.Sprint("key", i), func() (interface{}, error) {
+			started.Done()
+			<-unblock
+			return nil, nil
+		}))
+	}
+	started.Wait()
+	if got := g.PendingCalls(); got != n {
+		t.Errorf("PendingCalls() = %d; want %d", got, n)
+	}
+
+	close(unblock)
+	for _, ch := range chans {
+		<-ch
+	}
+	if got := g.PendingCalls(); got != 0 {
+		t.Errorf("PendingCalls() after the calls completed = %d; want 0", got)
+	}
+}
+
+// TestDoChanSharedWithDo checks that callers joining a call through Do and
+// DoChan all see the same result and agree that it was shared.
+func TestDoChanSharedWithDo(t *testing.T) {
+	var g Group
+	unblock := make(chan struct{})
+	var calls int32
+	fn := func() (interface{}, error) {
+		atomic.AddInt32(&calls, 1)
+		<-unblock
+		return "bar", nil
+	}
+
+	first := g.DoChan("key", fn)
+
+	type doResult struct {
+		v      interface{}
+		err    error
+		shared bool
+	}
+	second := make(chan doResult, 1)
+	go func() {
+		v, err, shared := g.Do("key", fn)
+		second <- doResult{v, err, shared}
+	}()
+	waitForDups(&g, "key", 1)
+
+	third := g.DoChan("key", fn)
+	waitForDups(&g, "key", 2)
+	close(unblock)
+
+	for i, ch := range []<-chan Result{first, third} {
+		if r := <-ch; r.Val != "bar" || r.Err != nil || !r.Shared {
+			t.Errorf("DoChan caller %d got %+v; want shared %q", i, r, "bar")
+		}
+	}
+	if r := <-second; r.v != "bar" || r.err != nil || !r.shared {
+		t.Errorf("Do caller got %+v; want shared %q", r, "bar")
+	}
+	if got := atomic.LoadInt32(&calls); got != 1 {
+		t.Errorf("fn called %d times; want 1", got)
+	}
+}
+
+func TestGoexitDoChan(t *testing.T) {
+	var g Group
+	ch := g.DoChan("key", func() (interface{}, error) {
+		runtime.Goexit()
+		return nil, nil
+	})
+
+	select {
+	case r := <-ch:
+		if r.Err != errGoexit {
+			t.Errorf("DoChan result error = %v; want %v", r.Err, errGoexit)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("DoChan hangs when fn calls runtime.Goexit")
+	}
+}
+
+func TestDefaultGroup(t *testing.T) {
+	const key = "singleflight.TestDefaultGroup"
+	started := make(chan struct{})
+	unblock := make(chan struct{})
+	var calls int32
+	fn := func() (interface{}, error) {
+		if atomic.AddInt32(&calls, 1) == 1 {
+			close(started)
+		}
+		<-unblock
+		return "bar", nil
+	}
+
+	first := DoChan(key, fn)
+	<-started
+	second := make(chan interface{}, 1)
+	go func() {
+		v, _, _ := Do(key, fn)
+		second <- v
+	}()
+	waitForDups(DefaultGroup, key, 1)
+
+	// After Forget, a new call runs fn again instead of joining.
+	Forget(key)
+	third := DoChan(key, fn)
+	close(unblock)
+
+	for _, v := range []interface{}{(<-first).Val, <-second, (<-third).Val} {
+		if v != "bar" {
+			t.Errorf("got %v; want %q", v, "bar")
+		}
+	}
+	if got := atomic.LoadInt32(&calls); got != 2 {
+		t.Errorf("fn called %d times; want 2", got)
+	}
+}
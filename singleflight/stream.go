@@ -0,0 +1,73 @@
+// Copyright 2026 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package singleflight
+
+import (
+	"bytes"
+	"errors"
+	"io"
+)
+
+// ErrStreamTooLarge is returned by DoStream when the stream returned by fn
+// is longer than the limit set with SetMaxStreamBytes.
+var ErrStreamTooLarge = errors.New("singleflight: stream exceeds maximum size")
+
+// ErrNotStream is returned by DoStream when the result shared for its key
+// was not produced by DoStream, and so is not a buffered stream.
+var ErrNotStream = errors.New("singleflight: result for key is not a stream")
+
+// SetMaxStreamBytes limits the number of bytes DoStream buffers from a
+// stream to n. An n <= 0, the default, sets no limit.
+func (g *Group) SetMaxStreamBytes(n int64) {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	g.maxStream = n
+}
+
+// DoStream is like Do for a function returning a stream, such as the body of
+// an HTTP response, which only one caller could read. The stream is read to
+// the end and closed once, and each caller, duplicate or not, gets its own
+// reader over the buffered bytes. Closing the returned reader has no effect.
+//
+// If the stream is longer than the limit set with SetMaxStreamBytes,
+// DoStream returns ErrStreamTooLarge without reading the stream further.
+// An error reading the stream is returned as the error of the call.
+//
+// A key used with DoStream should not be used with other methods of g that
+// call fn, or with Set. If DoStream joins such a call, or finds its retained
+// result, and the result is not a stream, DoStream returns ErrNotStream.
+func (g *Group) DoStream(key string, fn func() (io.ReadCloser, error)) (r io.ReadCloser, err error, shared bool) {
+	v, err, shared := g.Do(key, func() (interface{}, error) {
+		g.mu.Lock()
+		limit := g.maxStream
+		g.mu.Unlock()
+
+		rc, err := fn()
+		if err != nil {
+			return nil, err
+		}
+		defer rc.Close()
+		var src io.Reader = rc
+		if limit > 0 {
+			src = io.LimitReader(rc, limit+1)
+		}
+		b, err := io.ReadAll(src)
+		if err != nil {
+			return nil, err
+		}
+		if limit > 0 && int64(len(b)) > limit {
+			return nil, ErrStreamTooLarge
+		}
+		return b, nil
+	})
+	if err != nil {
+		return nil, err, shared
+	}
+	b, ok := v.([]byte)
+	if !ok {
+		return nil, ErrNotStream, shared
+	}
+	return io.NopCloser(bytes.NewReader(b)), nil, shared
+}
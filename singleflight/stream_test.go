@@ -0,0 +1,99 @@
+// Copyright 2026 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package singleflight
+
+import (
+	"io"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+)
+
+type countingCloser struct {
+	io.Reader
+	closed *int32
+}
+
+func (c countingCloser) Close() error {
+	atomic.AddInt32(c.closed, 1)
+	return nil
+}
+
+func TestDoStream(t *testing.T) {
+	var g Group
+	const body = "the quick brown fox"
+	var calls, closed int32
+	unblock := make(chan struct{})
+	fn := func() (io.ReadCloser, error) {
+		atomic.AddInt32(&calls, 1)
+		<-unblock
+		return countingCloser{strings.NewReader(body), &closed}, nil
+	}
+
+	const n = 5
+	var wg sync.WaitGroup
+	for i := 0; i < n; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			r, err, _ := g.DoStream("key", fn)
+			if err != nil {
+				t.Errorf("DoStream error = %v", err)
+				return
+			}
+			defer r.Close()
+			b, err := io.ReadAll(r)
+			if string(b) != body || err != nil {
+				t.Errorf("reading DoStream result = %q, %v; want %q, nil", b, err, body)
+			}
+		}()
+	}
+	waitForDups(&g, "key", n-1)
+	close(unblock)
+	wg.Wait()
+
+	if got := atomic.LoadInt32(&calls); got != 1 {
+		t.Errorf("fn called %d times; want 1", got)
+	}
+	if got := atomic.LoadInt32(&closed); got != 1 {
+		t.Errorf("source closed %d times; want 1", got)
+	}
+}
+
+func TestDoStreamTooLarge(t *testing.T) {
+	var g Group
+	g.SetMaxStreamBytes(4)
+	var closed int32
+	fn := func() (io.ReadCloser, error) {
+		return countingCloser{strings.NewReader("12345"), &closed}, nil
+	}
+	if _, err, _ := g.DoStream("key", fn); err != ErrStreamTooLarge {
+		t.Errorf("DoStream of an oversized stream = %v; want %v", err, ErrStreamTooLarge)
+	}
+	if closed != 1 {
+		t.Error("oversized source not closed")
+	}
+
+	g.SetMaxStreamBytes(5)
+	if r, err, _ := g.DoStream("key", fn); err != nil {
+		t.Errorf("DoStream of a stream at the limit = %v", err)
+	} else if b, _ := io.ReadAll(r); string(b) != "12345" {
+		t.Errorf("DoStream of a stream at the limit read %q; want %q", b, "12345")
+	}
+}
+
+func TestDoStreamNotStream(t *testing.T) {
+	var g Group
+	g.SetMaxBytes(10)
+	g.Set("key", "not a stream", nil, 0)
+	r, err, _ := g.DoStream("key", func() (io.ReadCloser, error) {
+		t.Error("DoStream called fn despite a retained result")
+		return io.NopCloser(strings.NewReader("")), nil
+	})
+	if r != nil || err != ErrNotStream {
+		t.Errorf("DoStream over a retained non-stream result = %v, %v; want nil, %v", r, err, ErrNotStream)
+	}
+}
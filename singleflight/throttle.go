@@ -0,0 +1,70 @@
+// Copyright 2026 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package singleflight
+
+import "time"
+
+// A throttled is the last result of a call made with DoThrottled.
+type throttled struct {
+	val      interface{}
+	err      error
+	done     time.Time     // when the call completed
+	interval time.Duration // the minInterval of the call
+}
+
+// DoThrottled is like Do, but calls fn for key at most once per minInterval:
+// within minInterval of the completion of the last call for key made through
+// DoThrottled, it returns that call's result, marked as shared, instead of
+// calling fn again. Unlike a memoized result, the last result is returned
+// whether or not it was an error.
+//
+// Forget discards the last result, so that the next call for key calls fn.
+// The last result is also discarded once it is older than minInterval.
+func (g *Group) DoThrottled(key string, minInterval time.Duration, fn func() (interface{}, error)) (v interface{}, err error, shared bool) {
+	g.mu.Lock()
+	if t, ok := g.throttled[key]; ok {
+		if g.now().Sub(t.done) < minInterval {
+			g.mu.Unlock()
+			return t.val, t.err, true
+		}
+		delete(g.throttled, key)
+	}
+	g.mu.Unlock()
+
+	return g.do(key, nil, func(c *call) func() (interface{}, error) {
+		return func() (interface{}, error) {
+			v, err := fn()
+			g.mu.Lock()
+			defer g.mu.Unlock()
+			// As in doCall, a call that was forgotten, or superseded, while
+			// fn ran no longer speaks for key.
+			if c.forgotten || g.m[key] != c {
+				return v, err
+			}
+			if g.throttled == nil {
+				g.throttled = make(map[string]*throttled)
+			}
+			now := g.now()
+			g.throttled[key] = &throttled{val: v, err: err, done: now, interval: minInterval}
+			if len(g.throttled) >= 2*g.throttledSwept {
+				g.sweepThrottled(now)
+			}
+			return v, err
+		}
+	})
+}
+
+// sweepThrottled discards the last results that are older than the
+// minInterval of their call. Sweeping only once the number of results has
+// doubled since the last sweep keeps its cost constant per call on average.
+// g.mu must be held.
+func (g *Group) sweepThrottled(now time.Time) {
+	for key, t := range g.throttled {
+		if now.Sub(t.done) >= t.interval {
+			delete(g.throttled, key)
+		}
+	}
+	g.throttledSwept = max(len(g.throttled), 1)
+}
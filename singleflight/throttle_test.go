@@ -0,0 +1,45 @@
+// Copyright 2026 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package singleflight
+
+import (
+	"errors"
+	"testing"
+	"time"
+)
+
+func TestDoThrottled(t *testing.T) {
+	var g Group
+	const interval = 20 * time.Millisecond
+	var runs []time.Time
+	fn := func() (interface{}, error) {
+		runs = append(runs, time.Now())
+		return len(runs), errors.New("flaky")
+	}
+
+	start := time.Now()
+	for time.Since(start) < 5*interval {
+		v, err, _ := g.DoThrottled("key", interval, fn)
+		if v != len(runs) || err == nil {
+			t.Fatalf("DoThrottled = %v, %v; want last result %d, error", v, err, len(runs))
+		}
+		time.Sleep(interval / 10)
+	}
+	if len(runs) < 2 {
+		t.Errorf("fn ran %d times in %v; want it to run again after each interval", len(runs), 5*interval)
+	}
+	for i := 1; i < len(runs); i++ {
+		if d := runs[i].Sub(runs[i-1]); d < interval {
+			t.Errorf("fn ran twice within %v; want at most once per %v", d, interval)
+		}
+	}
+
+	n := len(runs)
+	g.Forget("key")
+	g.DoThrottled("key", interval, fn)
+	if len(runs) != n+1 {
+		t.Error("DoThrottled after Forget did not call fn")
+	}
+}